@@ -0,0 +1,174 @@
+package alerts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/erikbryant/options/security"
+)
+
+// Window is how long after alerting on a contract we stay quiet about it
+const Window = 7 * 24 * time.Hour
+
+// MaxLines is the most contracts one message lists
+const MaxLines = 10
+
+// Log records when we last alerted on each contract, so we do not repeat ourselves
+type Log struct {
+	Sent map[string]time.Time `json:"sent"`
+}
+
+// LoadLog reads the log from file. A missing file is an empty log.
+func LoadLog(file string) (*Log, error) {
+	l := &Log{Sent: map[string]time.Time{}}
+
+	contents, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read alert log %s %s", file, err)
+	}
+
+	err = json.Unmarshal(contents, l)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal alert log %s %s", file, err)
+	}
+	if l.Sent == nil {
+		l.Sent = map[string]time.Time{}
+	}
+
+	return l, nil
+}
+
+// Save writes the log to file, dropping entries too old to matter
+func (l *Log) Save(file string, now time.Time) error {
+	for key, sent := range l.Sent {
+		if now.Sub(sent) >= Window {
+			delete(l.Sent, key)
+		}
+	}
+
+	s, err := json.MarshalIndent(l, "", " ")
+	if err != nil {
+		return fmt.Errorf("could not marshal alert log %s", err)
+	}
+
+	return os.WriteFile(file, s, 0644)
+}
+
+// Seen returns whether we alerted on the key within the window
+func (l *Log) Seen(key string, now time.Time) bool {
+	sent, ok := l.Sent[key]
+	return ok && now.Sub(sent) < Window
+}
+
+// kind returns "put" or "call"
+func kind(s security.Selection) string {
+	if s.Call {
+		return "call"
+	}
+	return "put"
+}
+
+// key identifies the contract posted to the webhook in the log, however many
+// rules or profiles match it. The webhook is a secret, so only its hash is kept.
+func key(webhook string, s security.Selection) string {
+	sum := sha256.Sum256([]byte(webhook))
+	return fmt.Sprintf("%x %s %s %s %.2f", sum[:8], s.Security.Ticker, kind(s), s.Contract.Expiration, s.Contract.Strike)
+}
+
+// line describes the contract in one line
+func line(s security.Selection) string {
+	c := s.Contract
+	return fmt.Sprintf("%s %s %s $%.2f @ $%.2f: %.1f%% annualized (%.1f%% net), delta %.2f",
+		s.Security.Ticker, c.Expiration, kind(s), c.Strike, c.Fill, c.Annualized, c.NetAnnualized, c.Delta)
+}
+
+// Message returns the alert text for the matching contracts
+func Message(profile, rule string, matches []security.Selection) string {
+	lines := []string{fmt.Sprintf("Options alert for %s (%s): %d contract(s)", profile, rule, len(matches))}
+	for i, s := range matches {
+		if i == MaxLines {
+			lines = append(lines, fmt.Sprintf("...and %d more", len(matches)-MaxLines))
+			break
+		}
+		lines = append(lines, line(s))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Post sends the text to a webhook. Slack reads the "text" field and
+// Discord the "content" field; each ignores the other.
+func Post(url, text string) error {
+	body, err := json.Marshal(map[string]string{"text": text, "content": text})
+	if err != nil {
+		return fmt.Errorf("could not marshal alert %s", err)
+	}
+
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("could not post alert %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("could not post alert: %s %s", resp.Status, b)
+	}
+
+	return nil
+}
+
+// Evaluate checks the profile's selections against each of its alert rules
+// and posts a message listing the contracts that match and that we have not
+// posted to the rule's webhook within the window, by this or any other rule
+// or profile. Contracts are only logged once delivered.
+func Evaluate(l *Log, profile string, rules []security.AlertRule, selections []security.Selection, now time.Time) error {
+	failures := []string{}
+
+	for _, rule := range rules {
+		webhook := os.ExpandEnv(rule.Webhook)
+		matches := []security.Selection{}
+		for _, s := range selections {
+			if rule.Matches(s) && !l.Seen(key(webhook, s), now) {
+				matches = append(matches, s)
+			}
+		}
+		if len(matches) == 0 {
+			continue
+		}
+
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].Contract.Annualized > matches[j].Contract.Annualized
+		})
+
+		err := Post(webhook, Message(profile, rule.Name, matches))
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", rule.Name, err))
+			continue
+		}
+
+		// Only the contracts the message listed count as alerted on
+		for i, s := range matches {
+			if i == MaxLines {
+				break
+			}
+			l.Sent[key(webhook, s)] = now
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("alerts for %s failed: %s", profile, strings.Join(failures, "; "))
+	}
+
+	return nil
+}
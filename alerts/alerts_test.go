@@ -0,0 +1,152 @@
+package alerts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/erikbryant/options/security"
+)
+
+// webhook returns a stand-in webhook and the messages posted to it
+func webhook(t *testing.T, status int) (*httptest.Server, *[]string) {
+	t.Helper()
+
+	posted := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]string{}
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil || body["text"] != body["content"] {
+			http.Error(w, "bad payload", http.StatusBadRequest)
+			return
+		}
+		posted = append(posted, body["text"])
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &posted
+}
+
+func TestEvaluate(t *testing.T) {
+	server, posted := webhook(t, http.StatusNoContent)
+	t.Setenv("TEST_WEBHOOK", server.URL)
+
+	ko := &security.Security{Ticker: "KO"}
+	selections := []security.Selection{
+		{Security: ko, Contract: security.Contract{Expiration: "2024-06-07", Strike: 60, Fill: 0.40, Annualized: 55, Delta: -0.10}},
+		{Security: ko, Contract: security.Contract{Expiration: "2024-06-07", Strike: 65, Fill: 0.90, Annualized: 70, Delta: -0.18}},
+		{Security: ko, Contract: security.Contract{Expiration: "2024-06-07", Strike: 70, Fill: 2.00, Annualized: 90, Delta: -0.45}},
+	}
+	rules := []security.AlertRule{{Name: "standout", MinAnnualized: 50, MaxDelta: 0.2, Webhook: "$TEST_WEBHOOK"}}
+
+	file := filepath.Join(t.TempDir(), "alerts.json")
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	l, err := LoadLog(file)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	err = Evaluate(l, "eb", rules, selections, now)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if len(*posted) != 1 {
+		t.Fatalf("Expected 1 message, got %v", *posted)
+	}
+	lines := strings.Split((*posted)[0], "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "KO 2024-06-07 put $65.00") {
+		t.Errorf("Unexpected message %q", (*posted)[0])
+	}
+
+	// Within the week the same contracts stay quiet, even across runs
+	err = l.Save(file, now)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	l, _ = LoadLog(file)
+	Evaluate(l, "eb", rules, selections, now.Add(3*24*time.Hour))
+	if len(*posted) != 1 {
+		t.Errorf("Expected no new messages, got %v", *posted)
+	}
+
+	// A week later they alert again
+	Evaluate(l, "eb", rules, selections, now.Add(Window))
+	if len(*posted) != 2 {
+		t.Errorf("Expected a second message, got %v", *posted)
+	}
+}
+
+func TestEvaluateRules(t *testing.T) {
+	team, teamPosted := webhook(t, http.StatusNoContent)
+	own, ownPosted := webhook(t, http.StatusNoContent)
+
+	ko := &security.Security{Ticker: "KO"}
+	selections := []security.Selection{{Security: ko, Contract: security.Contract{Expiration: "2024-06-07", Strike: 65, Annualized: 70, Delta: -0.18}}}
+	rules := []security.AlertRule{
+		{Name: "standout", MinAnnualized: 50, Webhook: team.URL},
+		{Name: "safe", MaxDelta: 0.2, Webhook: team.URL},
+		{Name: "mine", MaxDelta: 0.2, Webhook: own.URL},
+	}
+
+	// A contract matching two rules on the same webhook is posted there once
+	l := &Log{Sent: map[string]time.Time{}}
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	err := Evaluate(l, "eb", rules, selections, now)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if len(*teamPosted) != 1 || len(*ownPosted) != 1 {
+		t.Errorf("Expected one message to each webhook, got %v and %v", *teamPosted, *ownPosted)
+	}
+
+	// Another profile's rules do not post it again within the week
+	Evaluate(l, "cc", rules[:1], selections, now.Add(time.Hour))
+	if len(*teamPosted) != 1 {
+		t.Errorf("Expected no new messages, got %v", *teamPosted)
+	}
+
+	// The log does not hold the webhooks
+	for key := range l.Sent {
+		if strings.Contains(key, team.URL) || strings.Contains(key, own.URL) {
+			t.Errorf("Expected the webhook to be hashed, got %s", key)
+		}
+	}
+}
+
+func TestEvaluateFailure(t *testing.T) {
+	server, _ := webhook(t, http.StatusInternalServerError)
+
+	ko := &security.Security{Ticker: "KO"}
+	selections := []security.Selection{{Security: ko, Contract: security.Contract{Expiration: "2024-06-07", Strike: 65, Annualized: 70}}}
+	rules := []security.AlertRule{{Name: "standout", MinAnnualized: 50, Webhook: server.URL}}
+
+	l := &Log{Sent: map[string]time.Time{}}
+	err := Evaluate(l, "eb", rules, selections, time.Now())
+	if err == nil {
+		t.Errorf("Expected an error")
+	}
+	if len(l.Sent) != 0 {
+		t.Errorf("Expected undelivered alerts not to be logged, got %v", l.Sent)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	ko := &security.Security{Ticker: "KO"}
+	matches := []security.Selection{}
+	for i := 0; i < MaxLines+3; i++ {
+		matches = append(matches, security.Selection{Security: ko, Contract: security.Contract{Strike: float64(50 + i)}})
+	}
+
+	lines := strings.Split(Message("eb", "standout", matches), "\n")
+	if len(lines) != MaxLines+2 {
+		t.Errorf("Expected %d lines, got %d", MaxLines+2, len(lines))
+	}
+	if lines[len(lines)-1] != "...and 3 more" {
+		t.Errorf("Unexpected last line %s", lines[len(lines)-1])
+	}
+}
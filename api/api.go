@@ -0,0 +1,461 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/erikbryant/options/date"
+	"github.com/erikbryant/options/options"
+	"github.com/erikbryant/options/report"
+	"github.com/erikbryant/options/screen"
+	"github.com/erikbryant/options/security"
+	"github.com/erikbryant/options/skiplist"
+)
+
+// Server answers questions about a snapshot of the securities a scan loaded.
+// It needs no network.
+type Server struct {
+	Snapshot   options.Snapshot
+	Profiles   []security.Params
+	Rules      *skiplist.Rules
+	ReportFile string
+
+	mu sync.Mutex // held from a run until its results are read, as runs set the clusters
+}
+
+// Info describes the snapshot being served
+type Info struct {
+	Date       string `json:"date"`
+	Expiration string `json:"expiration"`
+	Securities int    `json:"securities"`
+}
+
+// Summary describes a security in the securities list
+type Summary struct {
+	Ticker       string  `json:"ticker"`
+	Name         string  `json:"name"`
+	Sector       string  `json:"sector"`
+	Industry     string  `json:"industry"`
+	Price        float64 `json:"price"`
+	EarningsDate string  `json:"earningsDate,omitempty"`
+	IV           float64 `json:"iv"`
+	IVRank       float64 `json:"ivRank"`
+	Puts         int     `json:"puts"`
+	Calls        int     `json:"calls"`
+}
+
+// Chain is a ticker's option chain
+type Chain struct {
+	Ticker      string              `json:"ticker"`
+	Price       float64             `json:"price"`
+	Expirations []string            `json:"expirations"`
+	Puts        []security.Contract `json:"puts"`
+	Calls       []security.Contract `json:"calls"`
+}
+
+// Row is a contract that passed a profile's filter
+type Row struct {
+	Ticker        string  `json:"ticker"`
+	Type          string  `json:"type"` // put or call
+	Expiration    string  `json:"expiration"`
+	Strike        float64 `json:"strike"`
+	Price         float64 `json:"price"`
+	Bid           float64 `json:"bid"`
+	Ask           float64 `json:"ask"`
+	Fill          float64 `json:"fill"`
+	Delta         float64 `json:"delta"`
+	IV            float64 `json:"iv"`
+	NetCredit     float64 `json:"netCredit"`
+	NetYield      float64 `json:"netYield"`
+	Annualized    float64 `json:"annualized"`
+	NetAnnualized float64 `json:"netAnnualized"`
+	Earnings      bool    `json:"earnings"` // earnings on or before the expiration
+	Lots          int     `json:"lots"`
+}
+
+// Filtered is the result of running a profile's filter
+type Filtered struct {
+	Profile    string `json:"profile"`
+	Expiration string `json:"expiration"`
+	Rows       []Row  `json:"rows"`
+}
+
+// Handler returns the API's routes
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/snapshot", s.snapshot)
+	mux.HandleFunc("GET /api/securities", s.securities)
+	mux.HandleFunc("GET /api/securities/{ticker}/chain", s.chain)
+	mux.HandleFunc("GET /api/profiles", s.profiles)
+	mux.HandleFunc("GET /api/overrides", s.overrides)
+	mux.HandleFunc("GET /api/profiles/{profile}/filter", s.filter)
+	mux.HandleFunc("GET /api/profiles/{profile}/export", s.export)
+	mux.HandleFunc("GET /api/report", s.report)
+	mux.Handle("GET /", http.FileServerFS(webFS()))
+	return mux
+}
+
+// reply writes v as JSON
+func reply(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// fail writes the error as JSON
+func fail(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// security returns the snapshot's security for the ticker
+func (s *Server) security(ticker string) (*security.Security, bool) {
+	ticker = strings.ToUpper(ticker)
+	for i := range s.Snapshot.Securities {
+		if s.Snapshot.Securities[i].Ticker == ticker {
+			return &s.Snapshot.Securities[i], true
+		}
+	}
+	return nil, false
+}
+
+// snapshot describes the snapshot being served
+func (s *Server) snapshot(w http.ResponseWriter, r *http.Request) {
+	reply(w, Info{
+		Date:       s.Snapshot.Date,
+		Expiration: s.Snapshot.Expiration,
+		Securities: len(s.Snapshot.Securities),
+	})
+}
+
+// securities lists the securities in the snapshot
+func (s *Server) securities(w http.ResponseWriter, r *http.Request) {
+	summaries := []Summary{}
+	for _, sec := range s.Snapshot.Securities {
+		summaries = append(summaries, Summary{
+			Ticker:       sec.Ticker,
+			Name:         sec.Name,
+			Sector:       sec.Sector,
+			Industry:     sec.Industry,
+			Price:        sec.Price,
+			EarningsDate: sec.EarningsDate,
+			IV:           sec.IV,
+			IVRank:       sec.IVRank,
+			Puts:         len(sec.Puts),
+			Calls:        len(sec.Calls),
+		})
+	}
+	reply(w, summaries)
+}
+
+// only returns the contracts with the given expiration (all, if it is empty)
+func only(contracts []security.Contract, expiration string) []security.Contract {
+	result := []security.Contract{}
+	for _, c := range contracts {
+		if expiration == "" || c.Expiration == expiration {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// chain returns a ticker's option chain, for one expiration if given
+func (s *Server) chain(w http.ResponseWriter, r *http.Request) {
+	sec, ok := s.security(r.PathValue("ticker"))
+	if !ok {
+		fail(w, http.StatusNotFound, fmt.Errorf("no security %s", r.PathValue("ticker")))
+		return
+	}
+
+	expirations := map[string]bool{}
+	for _, c := range append(append([]security.Contract{}, sec.Puts...), sec.Calls...) {
+		expirations[c.Expiration] = true
+	}
+
+	chain := Chain{Ticker: sec.Ticker, Price: sec.Price, Expirations: []string{}}
+	for expiration := range expirations {
+		chain.Expirations = append(chain.Expirations, expiration)
+	}
+	sort.Strings(chain.Expirations)
+
+	expiration := r.URL.Query().Get("expiration")
+	if expiration != "" && !expirations[expiration] {
+		fail(w, http.StatusNotFound, fmt.Errorf("%s has no options expiring %s", sec.Ticker, expiration))
+		return
+	}
+	chain.Puts = only(sec.Puts, expiration)
+	chain.Calls = only(sec.Calls, expiration)
+
+	reply(w, chain)
+}
+
+// redacted returns a copy of the profile without where it posts and uploads
+// to. Webhook URLs are credentials, and the server has no authentication.
+func redacted(p security.Params) security.Params {
+	p.Folder = ""
+	p.ArchiveFolder = ""
+	p.Alerts = append([]security.AlertRule{}, p.Alerts...)
+	for i := range p.Alerts {
+		p.Alerts[i].Webhook = ""
+	}
+	return p
+}
+
+// profiles lists the profiles and their settings, less any secrets
+func (s *Server) profiles(w http.ResponseWriter, r *http.Request) {
+	profiles := []security.Params{}
+	for _, p := range s.Profiles {
+		profiles = append(profiles, redacted(p))
+	}
+	reply(w, profiles)
+}
+
+// Overridable are the profile settings a query may override: those that
+// decide which contracts are listed and how they are sized
+var Overridable = []string{
+	"MinPrice", "MaxPrice", "MinYield", "MinSafetySpread", "MinCallSpread", "MinIfCalled",
+	"MaxAge", "MaxAgeCalendar", "MinDTE", "MaxDTE", "MinTradingDTE", "MaxTradingDTE", "MinHours", "MaxHours",
+	"MinIVHVRatio", "MinIVRank", "MinOpenInterest", "MinVolume", "MaxSpread", "MaxSpreadPct", "MinLiquidity",
+	"Itm", "MinNetCredit", "Cash", "MaxTickerPct", "MaxSectorPct", "MaxKelly",
+	"Diversify", "Correlation", "CorrelationDays",
+}
+
+// overrides lists the settings a query may override
+func (s *Server) overrides(w http.ResponseWriter, r *http.Request) {
+	reply(w, Overridable)
+}
+
+// Override sets the profile's settings named in the query (case
+// insensitively, e.g. minYield=2&itm=false). Only the Overridable settings
+// can be overridden; naming any other setting is an error and other query
+// parameters are ignored.
+func Override(p *security.Params, query url.Values) error {
+	v := reflect.ValueOf(p).Elem()
+	t := v.Type()
+
+	settings := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		settings[strings.ToLower(t.Field(i).Name)] = true
+	}
+	overridable := map[string]string{}
+	for _, name := range Overridable {
+		overridable[strings.ToLower(name)] = name
+	}
+
+	for name, values := range query {
+		if len(values) == 0 {
+			continue
+		}
+		field, ok := overridable[strings.ToLower(name)]
+		if !ok {
+			if settings[strings.ToLower(name)] {
+				return fmt.Errorf("%s cannot be overridden", name)
+			}
+			continue
+		}
+		setting := v.FieldByName(field)
+		value := values[len(values)-1]
+
+		var err error
+		switch setting.Kind() {
+		case reflect.Float64:
+			var f float64
+			f, err = strconv.ParseFloat(value, 64)
+			setting.SetFloat(f)
+		case reflect.Int, reflect.Int64:
+			var n int64
+			n, err = strconv.ParseInt(value, 10, 64)
+			setting.SetInt(n)
+		case reflect.Bool:
+			var b bool
+			b, err = strconv.ParseBool(value)
+			setting.SetBool(b)
+		}
+		if err != nil {
+			return fmt.Errorf("bad %s '%s' %s", name, value, err)
+		}
+	}
+
+	return nil
+}
+
+// allowed returns the selections, less any the profile's skip rules exclude
+func (s *Server) allowed(selections []security.Selection, profile, today string) []security.Selection {
+	if s.Rules == nil {
+		return selections
+	}
+
+	result := []security.Selection{}
+	for _, sel := range selections {
+		if _, skip := s.Rules.Skip(sel.Security, profile, today); !skip {
+			result = append(result, sel)
+		}
+	}
+	return result
+}
+
+// rows returns the selections as rows
+func rows(selections []security.Selection) []Row {
+	rows := []Row{}
+	for _, sel := range selections {
+		c := sel.Contract
+		kind := "put"
+		if sel.Call {
+			kind = "call"
+		}
+		earnings := sel.Security.EarningsDate
+		rows = append(rows, Row{
+			Ticker:        sel.Security.Ticker,
+			Type:          kind,
+			Expiration:    c.Expiration,
+			Strike:        c.Strike,
+			Price:         sel.Security.Price,
+			Bid:           c.Bid,
+			Ask:           c.Ask,
+			Fill:          c.Fill,
+			Delta:         c.Delta,
+			IV:            c.IV,
+			NetCredit:     c.NetCredit,
+			NetYield:      c.NetYield,
+			Annualized:    c.Annualized,
+			NetAnnualized: c.NetAnnualized,
+			Earnings:      earnings != "" && earnings <= c.Expiration,
+			Lots:          c.Lots,
+		})
+	}
+	return rows
+}
+
+// profile returns the profile with the given initials
+func (s *Server) profile(initials string) (security.Params, bool) {
+	for _, p := range s.Profiles {
+		if p.Initials == initials {
+			return p, true
+		}
+	}
+	return security.Params{}, false
+}
+
+// selection is a profile's filter run over the snapshot
+type selection struct {
+	p           security.Params // with any overrides
+	expiration  string
+	puts, calls []security.Selection
+}
+
+// run runs the profile's filter (with any overrides) over the snapshot. It
+// sets the underlyings' clusters, so the caller holds s.mu until it has read
+// them (e.g. through a sheet's cluster column).
+func (s *Server) run(initials string, query url.Values) (selection, error) {
+	p, ok := s.profile(initials)
+	if !ok {
+		return selection{}, fmt.Errorf("no profile %s", initials)
+	}
+
+	err := Override(&p, query)
+	if err != nil {
+		return selection{}, err
+	}
+
+	// The snapshot only has contracts through its expiration
+	expiration := s.Snapshot.Expiration
+	if e := query.Get("expiration"); e != "" {
+		if e > expiration {
+			return selection{}, fmt.Errorf("expiration %s is after the snapshot's %s", e, expiration)
+		}
+		expiration = e
+	}
+
+	today := date.Format(time.Now())
+	skip := func(selections []security.Selection) []security.Selection {
+		return s.allowed(selections, p.Initials, today)
+	}
+
+	puts, calls, _ := screen.Run(s.Snapshot.Securities, expiration, p, query.Get("type"), skip)
+
+	return selection{p: p, expiration: expiration, puts: puts, calls: calls}, nil
+}
+
+// Filter runs the profile's filter (with any overrides) over the snapshot
+func (s *Server) Filter(initials string, query url.Values) (Filtered, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sel, err := s.run(initials, query)
+	if err != nil {
+		return Filtered{}, err
+	}
+
+	return Filtered{Profile: sel.p.Initials, Expiration: sel.expiration, Rows: append(rows(sel.puts), rows(sel.calls)...)}, nil
+}
+
+// filter runs a profile's filter
+func (s *Server) filter(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.profile(r.PathValue("profile")); !ok {
+		fail(w, http.StatusNotFound, fmt.Errorf("no profile %s", r.PathValue("profile")))
+		return
+	}
+
+	filtered, err := s.Filter(r.PathValue("profile"), r.URL.Query())
+	if err != nil {
+		fail(w, http.StatusBadRequest, err)
+		return
+	}
+	reply(w, filtered)
+}
+
+// export returns the puts (or, with type=call, the calls) that pass a
+// profile's filter as CSV, laid out as the profile's sheet is
+func (s *Server) export(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.profile(r.PathValue("profile")); !ok {
+		fail(w, http.StatusNotFound, fmt.Errorf("no profile %s", r.PathValue("profile")))
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Get("type") == "" {
+		query.Set("type", "put")
+	}
+
+	// The sheet's cluster column reads what this run clustered
+	s.mu.Lock()
+	sel, err := s.run(r.PathValue("profile"), query)
+	if err != nil {
+		s.mu.Unlock()
+		fail(w, http.StatusBadRequest, err)
+		return
+	}
+
+	name := sel.p.Initials + "_" + sel.expiration + "_puts.csv"
+	output := security.FormatPuts(sel.puts, sel.expiration, sel.p)
+	if query.Get("type") == "call" {
+		name = sel.p.Initials + "_" + sel.expiration + "_calls.csv"
+		output = security.FormatCalls(sel.calls, sel.expiration, sel.p)
+	}
+	s.mu.Unlock()
+	if output == "" {
+		output = security.NoMatches(sel.expiration, sel.p)
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	fmt.Fprint(w, output)
+}
+
+// report returns the last run's report
+func (s *Server) report(w http.ResponseWriter, r *http.Request) {
+	rep, err := report.Load(s.ReportFile)
+	if err != nil {
+		fail(w, http.StatusNotFound, err)
+		return
+	}
+	reply(w, rep)
+}
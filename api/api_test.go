@@ -0,0 +1,288 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/erikbryant/options/options"
+	"github.com/erikbryant/options/security"
+	"github.com/erikbryant/options/skiplist"
+)
+
+// testServer returns a server over a small snapshot
+func testServer(t *testing.T) *Server {
+	t.Helper()
+
+	rules, err := skiplist.Parse([]byte(`{"version": 1, "skip": [{"ticker": "T", "reason": "test", "profiles": ["eb"]}], "allow": []}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Server{
+		Snapshot: options.Snapshot{
+			Date:       "2024-06-08",
+			Expiration: "2024-06-14",
+			Securities: []security.Security{
+				{
+					Ticker: "KO",
+					Price:  62,
+					Puts: []security.Contract{
+						{Expiration: "2024-06-07", Strike: 60, Bid: 0.60, Ask: 0.70},
+						{Expiration: "2024-06-14", Strike: 60, Bid: 0.90, Ask: 1.00},
+						{Expiration: "2024-06-14", Strike: 55, Bid: 0.20, Ask: 0.30},
+					},
+					Calls: []security.Contract{
+						{Expiration: "2024-06-14", Strike: 65, Bid: 0.20, Ask: 0.30},
+					},
+				},
+				{
+					Ticker: "T",
+					Price:  18,
+					Puts:   []security.Contract{{Expiration: "2024-06-14", Strike: 18, Bid: 0.40, Ask: 0.45}},
+				},
+			},
+		},
+		Profiles: []security.Params{{
+			Initials: "eb", MaxPrice: 100, MinYield: 1.0, Itm: true,
+			Folder: "drive-folder", ArchiveFolder: "drive-archive",
+			Alerts:  []security.AlertRule{{Name: "standout", MinAnnualized: 50, Webhook: "https://hooks.example.com/secret"}},
+			PutCols: []string{"ticker", "strike", "bid"}, CallCols: []string{"ticker", "strike", "bid"},
+		}},
+		Rules:      rules,
+		ReportFile: filepath.Join(t.TempDir(), "run-report.json"),
+	}
+}
+
+// get fetches the path from the server, decoding the JSON reply into v
+func get(t *testing.T, server *httptest.Server, path string, v any) int {
+	t.Helper()
+
+	resp, err := http.Get(server.URL + path)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	defer resp.Body.Close()
+
+	if v != nil && resp.StatusCode == http.StatusOK {
+		err = json.NewDecoder(resp.Body).Decode(v)
+		if err != nil {
+			t.Fatalf("Unable to decode %s %s", path, err)
+		}
+	}
+
+	return resp.StatusCode
+}
+
+func TestHandler(t *testing.T) {
+	s := testServer(t)
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	var summaries []Summary
+	if status := get(t, server, "/api/securities", &summaries); status != http.StatusOK || len(summaries) != 2 || summaries[0].Puts != 3 {
+		t.Errorf("Unexpected securities %d %+v", status, summaries)
+	}
+
+	var chain Chain
+	status := get(t, server, "/api/securities/ko/chain?expiration=2024-06-14", &chain)
+	if status != http.StatusOK || len(chain.Expirations) != 2 || len(chain.Puts) != 2 || len(chain.Calls) != 1 {
+		t.Errorf("Unexpected chain %d %+v", status, chain)
+	}
+
+	// The rows the sheets would have, less the tickers skipped for the profile
+	var filtered Filtered
+	status = get(t, server, "/api/profiles/eb/filter", &filtered)
+	if status != http.StatusOK || len(filtered.Rows) != 2 {
+		t.Errorf("Unexpected filter %d %+v", status, filtered)
+	}
+
+	// The profiles are listed without where they post and upload to
+	var profiles []security.Params
+	if status := get(t, server, "/api/profiles", &profiles); status != http.StatusOK || len(profiles) != 1 || profiles[0].MinYield != 1.0 {
+		t.Errorf("Unexpected profiles %d %+v", status, profiles)
+	}
+	if p := profiles[0]; p.Folder != "" || p.ArchiveFolder != "" || len(p.Alerts) != 1 || p.Alerts[0].Webhook != "" {
+		t.Errorf("Expected the profile to be redacted, got %+v", p)
+	}
+	if s.Profiles[0].Alerts[0].Webhook == "" {
+		t.Errorf("Redacting modified the server's profile")
+	}
+
+	var overridable []string
+	if status := get(t, server, "/api/overrides", &overridable); status != http.StatusOK || len(overridable) != len(Overridable) {
+		t.Errorf("Unexpected overrides %d %v", status, overridable)
+	}
+
+	// Overrides loosen the profile's filter
+	status = get(t, server, "/api/profiles/eb/filter?minYield=0.1&minSafetySpread=-10&type=call", &filtered)
+	if status != http.StatusOK || len(filtered.Rows) != 1 || filtered.Rows[0].Type != "call" {
+		t.Errorf("Unexpected filter %d %+v", status, filtered)
+	}
+
+	testCases := []struct {
+		path     string
+		expected int
+	}{
+		{"/api/securities/XYZ/chain", http.StatusNotFound},
+		{"/api/securities/KO/chain?expiration=2024-06-21", http.StatusNotFound},
+		{"/api/profiles/zz/filter", http.StatusNotFound},
+		{"/api/profiles/eb/filter?minYield=lots", http.StatusBadRequest},
+		{"/api/profiles/eb/filter?initials=cc", http.StatusBadRequest},
+		{"/api/profiles/eb/export?folder=x", http.StatusBadRequest},
+		{"/api/profiles/eb/filter?expiration=2024-06-21", http.StatusBadRequest},
+		{"/api/report", http.StatusNotFound},
+	}
+	for _, testCase := range testCases {
+		status := get(t, server, testCase.path, nil)
+		if status != testCase.expected {
+			t.Errorf("ERROR: For %s expected %d, got %d", testCase.path, testCase.expected, status)
+		}
+	}
+
+	err := os.WriteFile(s.ReportFile, []byte(`{"sheets": [{"profile": "eb", "name": "eb_2024-06-14_puts.csv", "rows": 2, "outcome": "uploaded"}]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rep struct{ Sheets []struct{ Name string } }
+	if status := get(t, server, "/api/report", &rep); status != http.StatusOK || len(rep.Sheets) != 1 {
+		t.Errorf("Unexpected report %d %+v", status, rep)
+	}
+}
+
+func TestWeb(t *testing.T) {
+	server := httptest.NewServer(testServer(t).Handler())
+	defer server.Close()
+
+	var info Info
+	if status := get(t, server, "/api/snapshot", &info); status != http.StatusOK || info.Date != "2024-06-08" || info.Securities != 2 {
+		t.Errorf("Unexpected snapshot %d %+v", status, info)
+	}
+
+	for _, path := range []string{"/", "/app.js", "/style.css"} {
+		if status := get(t, server, path, nil); status != http.StatusOK {
+			t.Errorf("ERROR: For %s expected %d, got %d", path, http.StatusOK, status)
+		}
+	}
+}
+
+// export fetches the CSV export at path, returning its filename and lines
+func export(t *testing.T, server *httptest.Server, path string) (string, []string) {
+	t.Helper()
+
+	resp, err := http.Get(server.URL + path)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/csv" {
+		t.Fatalf("Unexpected export %s %d %s", path, resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	return resp.Header.Get("Content-Disposition"), strings.Split(strings.TrimSpace(string(body)), "\n")
+}
+
+func TestExport(t *testing.T) {
+	server := httptest.NewServer(testServer(t).Handler())
+	defer server.Close()
+
+	testCases := []struct {
+		path     string
+		filename string
+		lines    int
+	}{
+		{"/api/profiles/eb/export", "eb_2024-06-14_puts.csv", security.HeaderRows + 2},
+		{"/api/profiles/eb/export?type=call&minYield=0.1&minSafetySpread=-10", "eb_2024-06-14_calls.csv", security.HeaderRows + 1},
+	}
+
+	for _, testCase := range testCases {
+		disposition, lines := export(t, server, testCase.path)
+		if !strings.Contains(disposition, testCase.filename) {
+			t.Errorf("ERROR: For %s expected %s, got %s", testCase.path, testCase.filename, disposition)
+		}
+		if len(lines) != testCase.lines {
+			t.Errorf("ERROR: For %s expected %d lines, got %d %q", testCase.path, testCase.lines, len(lines), lines)
+		}
+	}
+
+	// With nothing passing the filter, the export says so
+	_, lines := export(t, server, "/api/profiles/eb/export?type=call")
+	expected := strings.TrimSpace(security.NoMatches("2024-06-14", security.Params{Initials: "eb"}))
+	if strings.Join(lines, "\n") != expected {
+		t.Errorf("ERROR: For no matches expected %q, got %q", expected, lines)
+	}
+
+	if status := get(t, server, "/api/profiles/zz/export", nil); status != http.StatusNotFound {
+		t.Errorf("ERROR: For an unknown profile expected %d, got %d", http.StatusNotFound, status)
+	}
+}
+
+func TestOverride(t *testing.T) {
+	p := security.Params{Initials: "eb", MinYield: 1.5}
+
+	err := Override(&p, url.Values{"minyield": {"2.5"}, "MaxAge": {"3"}, "itm": {"true"}, "type": {"put"}})
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if p.MinYield != 2.5 || p.MaxAge != 3 || !p.Itm {
+		t.Errorf("Unexpected overrides %+v", p)
+	}
+
+	// Every overridable setting is a number or boolean of the profile
+	v := reflect.ValueOf(p)
+	for _, name := range Overridable {
+		kind := v.FieldByName(name).Kind()
+		if kind != reflect.Float64 && kind != reflect.Int64 && kind != reflect.Int && kind != reflect.Bool {
+			t.Errorf("ERROR: For %s expected a number or boolean setting, got %s", name, kind)
+		}
+	}
+
+	for _, query := range []url.Values{{"minYield": {"x"}}, {"itm": {"maybe"}}, {"putCols": {"ticker"}}, {"initials": {"cc"}}, {"Folder": {"x"}}, {"archiveFolder": {"x"}}, {"maxSectorTrades": {"1"}}} {
+		if Override(&p, query) == nil {
+			t.Errorf("ERROR: For %v expected an error", query)
+		}
+	}
+}
+
+func TestConcurrentRuns(t *testing.T) {
+	s := testServer(t)
+	s.Profiles[0].PutCols = append(s.Profiles[0].PutCols, "cluster")
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	// Each run sets the clusters, so runs with different settings take turns
+	done := make(chan bool)
+	for _, correlation := range []string{"0.1", "0.99"} {
+		go func() {
+			for i := 0; i < 10; i++ {
+				for _, path := range []string{"/api/profiles/eb/export?correlation=", "/api/profiles/eb/filter?correlation="} {
+					resp, err := http.Get(server.URL + path + correlation)
+					if err != nil {
+						t.Errorf("Unexpected error %s", err)
+						continue
+					}
+					io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+					if resp.StatusCode != http.StatusOK {
+						t.Errorf("ERROR: For %s expected %d, got %d", path+correlation, http.StatusOK, resp.StatusCode)
+					}
+				}
+			}
+			done <- true
+		}()
+	}
+	<-done
+	<-done
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// The web UI, built into the binary
+//
+//go:embed web
+var web embed.FS
+
+// webFS returns the web UI's files
+func webFS() fs.FS {
+	sub, err := fs.Sub(web, "web")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
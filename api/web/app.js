@@ -0,0 +1,202 @@
+"use strict";
+
+// get fetches a JSON API path, throwing the API's error if it fails
+async function get(path) {
+  const resp = await fetch(path);
+  const body = await resp.json();
+  if (!resp.ok) {
+    throw new Error(body.error || resp.statusText);
+  }
+  return body;
+}
+
+// cell returns a table cell holding the text
+function cell(text, className) {
+  const td = document.createElement("td");
+  td.textContent = text;
+  if (className) {
+    td.className = className;
+  }
+  return td;
+}
+
+// fixed formats a number, or nothing if there is none
+function fixed(n, digits) {
+  return n === undefined || n === null ? "" : n.toFixed(digits);
+}
+
+// showError replaces the table's rows with the error
+function showError(tbody, err) {
+  tbody.replaceChildren();
+  const tr = document.createElement("tr");
+  const td = cell(err.message, "error");
+  td.colSpan = 20;
+  tr.appendChild(td);
+  tbody.appendChild(tr);
+}
+
+// The strike ladder: calls on the left, puts on the right, one row per strike
+
+let chain = null;
+
+async function loadChain() {
+  const ticker = document.getElementById("ticker").value.trim();
+  const tbody = document.querySelector("#ladder tbody");
+  if (!ticker) {
+    return;
+  }
+  try {
+    chain = await get("/api/securities/" + encodeURIComponent(ticker) + "/chain");
+  } catch (err) {
+    chain = null;
+    showError(tbody, err);
+    return;
+  }
+
+  const select = document.getElementById("expiration");
+  select.replaceChildren(...chain.expirations.map((e) => new Option(e, e)));
+  showLadder();
+}
+
+function showLadder() {
+  const tbody = document.querySelector("#ladder tbody");
+  tbody.replaceChildren();
+  if (!chain) {
+    return;
+  }
+
+  const expiration = document.getElementById("expiration").value;
+  const strikes = new Map();
+  for (const [side, contracts] of [["call", chain.calls], ["put", chain.puts]]) {
+    for (const c of contracts) {
+      if (c.Expiration !== expiration) {
+        continue;
+      }
+      const row = strikes.get(c.Strike) || {};
+      row[side] = c;
+      strikes.set(c.Strike, row);
+    }
+  }
+
+  let priceMarked = false;
+  for (const strike of [...strikes.keys()].sort((a, b) => a - b)) {
+    const row = strikes.get(strike);
+    const tr = document.createElement("tr");
+    if (!priceMarked && strike >= chain.price) {
+      tr.className = "price";
+      tr.title = "Share price " + chain.price.toFixed(2);
+      priceMarked = true;
+    }
+    const side = (c, itm) => {
+      const className = itm ? "itm" : "";
+      return c
+        ? [cell(fixed(c.Bid, 2), className), cell(fixed(c.Ask, 2), className), cell(fixed(c.Delta, 2), className), cell(fixed(c.IV, 1), className)]
+        : [cell(""), cell(""), cell(""), cell("")];
+    };
+    tr.append(...side(row.call, strike < chain.price), cell(strike.toFixed(2), "strike"), ...side(row.put, strike > chain.price));
+    tbody.appendChild(tr);
+  }
+}
+
+// The screener: a form of the profile's settings, and the contracts that pass them
+
+let profiles = [];
+let overridable = [];
+
+// query returns the screener's settings that differ from the profile's, as a query string
+function query() {
+  const profile = profiles.find((p) => p.Initials === document.getElementById("profile").value);
+  const q = new URLSearchParams();
+  q.set("type", document.getElementById("type").value);
+  const through = document.getElementById("through").value.trim();
+  if (through) {
+    q.set("expiration", through);
+  }
+  for (const input of document.querySelectorAll("#params input")) {
+    const value = input.type === "checkbox" ? input.checked : input.type === "number" ? Number(input.value) : input.value;
+    if (value !== profile[input.name]) {
+      q.set(input.name, String(value));
+    }
+  }
+  return q.toString();
+}
+
+// showParams builds an input for each of the profile's settings a query may override
+function showParams() {
+  const profile = profiles.find((p) => p.Initials === document.getElementById("profile").value);
+  const fieldset = document.getElementById("params");
+  fieldset.replaceChildren();
+  for (const name of overridable) {
+    const value = profile[name];
+    const input = document.createElement("input");
+    input.name = name;
+    if (typeof value === "boolean") {
+      input.type = "checkbox";
+      input.checked = value;
+    } else {
+      input.type = "number";
+      input.step = "any";
+      input.value = value;
+    }
+    const label = document.createElement("label");
+    label.append(name + " ", input);
+    fieldset.appendChild(label);
+  }
+  updateExport();
+}
+
+function updateExport() {
+  const profile = document.getElementById("profile").value;
+  document.getElementById("export").href = "/api/profiles/" + encodeURIComponent(profile) + "/export?" + query();
+}
+
+async function screen(event) {
+  event.preventDefault();
+  updateExport();
+
+  const tbody = document.querySelector("#results tbody");
+  const profile = document.getElementById("profile").value;
+  let filtered;
+  try {
+    filtered = await get("/api/profiles/" + encodeURIComponent(profile) + "/filter?" + query());
+  } catch (err) {
+    showError(tbody, err);
+    return;
+  }
+
+  tbody.replaceChildren();
+  for (const r of filtered.rows) {
+    const tr = document.createElement("tr");
+    tr.append(
+      cell(r.ticker), cell(r.expiration), cell(fixed(r.strike, 2)), cell(fixed(r.price, 2)),
+      cell(fixed(r.bid, 2)), cell(fixed(r.ask, 2)), cell(fixed(r.fill, 2)), cell(fixed(r.delta, 2)), cell(fixed(r.iv, 1)),
+      cell(fixed(r.netCredit, 2)), cell(fixed(r.annualized, 1) + "%"), cell(fixed(r.netAnnualized, 1) + "%"),
+      cell(r.earnings ? "E" : "", "earnings"), cell(String(r.lots)),
+    );
+    tbody.appendChild(tr);
+  }
+}
+
+async function init() {
+  const snapshot = await get("/api/snapshot");
+  document.getElementById("snapshot").textContent =
+    snapshot.securities + " securities saved " + snapshot.date + ", expirations through " + snapshot.expiration;
+  document.getElementById("through").placeholder = snapshot.expiration;
+
+  const securities = await get("/api/securities");
+  document.getElementById("tickers").replaceChildren(...securities.map((s) => new Option(s.name, s.ticker)));
+
+  profiles = await get("/api/profiles");
+  overridable = await get("/api/overrides");
+  document.getElementById("profile").replaceChildren(...profiles.map((p) => new Option(p.Initials, p.Initials)));
+  showParams();
+
+  document.getElementById("ticker").addEventListener("change", loadChain);
+  document.getElementById("chain-form").addEventListener("submit", (event) => { event.preventDefault(); loadChain(); });
+  document.getElementById("expiration").addEventListener("change", showLadder);
+  document.getElementById("profile").addEventListener("change", showParams);
+  document.getElementById("screener").addEventListener("change", updateExport);
+  document.getElementById("screener").addEventListener("submit", screen);
+}
+
+init();
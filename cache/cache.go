@@ -6,6 +6,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
 )
 
 const cacheDir = "./web-request-cache/"
@@ -53,3 +54,69 @@ func Update(id string, contents map[string]interface{}) {
 		fmt.Println("Error writing cache file", err)
 	}
 }
+
+// Stats describes what the cache holds
+type Stats struct {
+	Entries int
+	Bytes   int64
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+// GetStats returns how many objects the cache holds, their size and the
+// times the oldest and newest were written
+func GetStats() (Stats, error) {
+	var stats Stats
+
+	entries, err := os.ReadDir(cacheDir)
+	if os.IsNotExist(err) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, fmt.Errorf("unable to read cache %s %s", cacheDir, err)
+	}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		stats.Entries++
+		stats.Bytes += info.Size()
+		if stats.Oldest.IsZero() || info.ModTime().Before(stats.Oldest) {
+			stats.Oldest = info.ModTime()
+		}
+		if info.ModTime().After(stats.Newest) {
+			stats.Newest = info.ModTime()
+		}
+	}
+
+	return stats, nil
+}
+
+// Purge removes cached objects last written more than maxAge before now and
+// returns how many it removed
+func Purge(maxAge time.Duration, now time.Time) (int, error) {
+	entries, err := os.ReadDir(cacheDir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("unable to read cache %s %s", cacheDir, err)
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || now.Sub(info.ModTime()) <= maxAge {
+			continue
+		}
+		err = os.Remove(path.Join(cacheDir, entry.Name()))
+		if err != nil {
+			return removed, fmt.Errorf("unable to purge %s %s", entry.Name(), err)
+		}
+		removed++
+	}
+
+	return removed, nil
+}
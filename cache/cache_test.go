@@ -1,7 +1,10 @@
 package cache
 
 import (
+	"os"
+	"path"
 	"testing"
+	"time"
 )
 
 func TestSanitize(t *testing.T) {
@@ -25,3 +28,55 @@ func TestSanitize(t *testing.T) {
 		}
 	}
 }
+
+func TestGetStats(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	stats, err := GetStats()
+	if err != nil || stats.Entries != 0 {
+		t.Errorf("Expected a missing cache to be empty, got %+v %v", stats, err)
+	}
+
+	err = os.Mkdir(cacheDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Update("old", map[string]interface{}{"a": 1.0})
+	Update("new", map[string]interface{}{"b": 2.0})
+	old := time.Now().Add(-4 * 24 * time.Hour).Truncate(time.Second)
+	os.Chtimes(path.Join(cacheDir, "old"), old, old)
+
+	stats, err = GetStats()
+	if err != nil || stats.Entries != 2 || stats.Bytes == 0 || !stats.Oldest.Equal(old) || !stats.Newest.After(old) {
+		t.Errorf("Unexpected stats %+v %v", stats, err)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	now := time.Now()
+	_, err := Purge(time.Hour, now)
+	if err != nil {
+		t.Errorf("Expected a missing cache to purge cleanly, got %s", err)
+	}
+
+	err = os.Mkdir(cacheDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Update("old", map[string]interface{}{"a": 1.0})
+	Update("new", map[string]interface{}{"b": 2.0})
+	os.Chtimes(path.Join(cacheDir, "old"), now.Add(-4*24*time.Hour), now.Add(-4*24*time.Hour))
+
+	removed, err := Purge(72*time.Hour, now)
+	if err != nil || removed != 1 {
+		t.Errorf("Expected 1 removed, got %d %v", removed, err)
+	}
+	if _, err := Read("old"); err == nil {
+		t.Errorf("Expected old to be purged")
+	}
+	if _, err := Read("new"); err != nil {
+		t.Errorf("Expected new to be kept, got %s", err)
+	}
+}
@@ -1,13 +1,51 @@
 package cboe
 
 import (
+	"bytes"
+	"encoding/csv"
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 
 	"github.com/erikbryant/web"
 )
 
+// Record is one row of the CBOE symbol directory
+type Record struct {
+	Ticker      string
+	Name        string            // company or fund name
+	ProductType string            // e.g. Equity, ETF, Index
+	ListDate    string            // when options were listed
+	Fields      map[string]string // every column, keyed by its header
+}
+
+// IsETF returns whether the record is an exchange traded product rather than
+// an equity. Without a product type we go by the name.
+func (r Record) IsETF() bool {
+	kind := strings.ToUpper(r.ProductType)
+	if kind != "" {
+		return strings.Contains(kind, "ETF") || strings.Contains(kind, "ETN") || strings.Contains(kind, "ETP")
+	}
+	name := " " + strings.ToUpper(r.Name) + " "
+	return strings.Contains(name, " ETF ") || strings.Contains(name, " ETN ")
+}
+
+// IsEquity returns whether the record is a single company's stock
+func (r Record) IsEquity() bool {
+	kind := strings.ToUpper(r.ProductType)
+	if kind != "" {
+		return strings.Contains(kind, "EQUITY") || strings.Contains(kind, "STOCK")
+	}
+	return !r.IsETF()
+}
+
+var (
+	mu      sync.Mutex
+	symbols = map[string]Record{} // the records loaded so far, by ticker
+	fetched bool                  // whether the directory has been downloaded (or tried)
+)
+
 // webRequest returns the downloaded payload
 func webRequest(url string) ([]byte, error) {
 	headers := map[string]string{}
@@ -27,8 +65,97 @@ func webRequest(url string) ([]byte, error) {
 	return body, nil
 }
 
-// WeeklyOptions returns options with weekly (or more frequent) expirations from the CBOE
-func WeeklyOptions() ([]string, error) {
+// columns maps the header of each column we know about to the index of that
+// column. The ticker is required; CBOE has put it in the last column.
+func columns(header []string) (map[string]int, error) {
+	cols := map[string]int{}
+
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case strings.Contains(name, "symbol") || strings.Contains(name, "ticker"):
+			cols["ticker"] = i
+		case strings.Contains(name, "type"):
+			cols["type"] = i
+		case strings.Contains(name, "date"):
+			cols["date"] = i
+		case strings.Contains(name, "name") && !strings.Contains(name, "dpm"):
+			cols["name"] = i
+		}
+	}
+
+	if _, ok := cols["ticker"]; !ok {
+		return nil, fmt.Errorf("no symbol column in header %q", header)
+	}
+
+	return cols, nil
+}
+
+// parse returns the records in a CBOE symbol directory CSV download
+func parse(body []byte) ([]Record, error) {
+	body = bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))
+
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("empty response")
+	}
+	if trimmed[0] == '<' {
+		return nil, fmt.Errorf("got HTML rather than CSV: %.80q", trimmed)
+	}
+
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.TrimLeadingSpace = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse CSV %s", err)
+	}
+
+	header := rows[0]
+	cols, err := columns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	field := func(row []string, col string) string {
+		i, ok := cols[col]
+		if !ok {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	records := []Record{}
+	for _, row := range rows[1:] {
+		ticker := strings.ToUpper(field(row, "ticker"))
+		if ticker == "" {
+			continue
+		}
+
+		r := Record{
+			Ticker:      ticker,
+			Name:        field(row, "name"),
+			ProductType: field(row, "type"),
+			ListDate:    field(row, "date"),
+			Fields:      map[string]string{},
+		}
+		for i, name := range header {
+			r.Fields[strings.TrimSpace(name)] = strings.TrimSpace(row[i])
+		}
+
+		records = append(records, r)
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no symbols in response")
+	}
+
+	return records, nil
+}
+
+// WeeklySymbols returns the CBOE directory records for options with weekly
+// (or more frequent) expirations
+func WeeklySymbols() ([]Record, error) {
 	url := "https://www.cboe.com/us/options/symboldir/weeklys_options/?download=csv"
 
 	response, err := webRequest(url)
@@ -36,21 +163,90 @@ func WeeklyOptions() ([]string, error) {
 		return nil, fmt.Errorf("unable to download CBOE data %v", err)
 	}
 
-	var tickers []string
+	records, err := parse(response)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse CBOE data %v", err)
+	}
 
-	rows := strings.Split(string(response), "\n")
-	for i, row := range rows {
-		if i == 0 {
-			continue
+	mu.Lock()
+	defer mu.Unlock()
+	fetched = true
+	for _, r := range records {
+		symbols[r.Ticker] = r
+	}
+
+	return records, nil
+}
+
+// Tickers returns the tickers of the records that pass the filter (all of them if nil)
+func Tickers(records []Record, filter func(Record) bool) []string {
+	tickers := []string{}
+	for _, r := range records {
+		if filter == nil || filter(r) {
+			tickers = append(tickers, r.Ticker)
 		}
-		if row == "" {
-			continue
+	}
+	return tickers
+}
+
+// WeeklyOptions returns options with weekly (or more frequent) expirations from the CBOE
+func WeeklyOptions() ([]string, error) {
+	records, err := WeeklySymbols()
+	if err != nil {
+		return nil, err
+	}
+
+	return Tickers(records, nil), nil
+}
+
+// Lookup returns the directory record for the ticker, downloading the
+// directory if it has not been yet
+func Lookup(ticker string) (Record, bool) {
+	mu.Lock()
+	r, ok := symbols[ticker]
+	tried := fetched
+	mu.Unlock()
+
+	if ok || tried {
+		return r, ok
+	}
+
+	_, err := WeeklySymbols()
+	if err != nil {
+		fmt.Println(err)
+		mu.Lock()
+		fetched = true
+		mu.Unlock()
+		return Record{}, false
+	}
+
+	return Lookup(ticker)
+}
+
+// Names returns the names of those of the tickers whose records are loaded,
+// without downloading anything
+func Names(tickers []string) map[string]string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	names := map[string]string{}
+	for _, ticker := range tickers {
+		if r, ok := symbols[ticker]; ok && r.Name != "" {
+			names[ticker] = r.Name
 		}
-		cols := strings.Split(row, ",")
-		ticker := cols[len(cols)-1]
-		ticker = strings.ReplaceAll(ticker, "\"", "")
-		tickers = append(tickers, ticker)
 	}
+	return names
+}
+
+// Remember records the names of tickers, as saved from an earlier download,
+// so Lookup finds them
+func Remember(names map[string]string) {
+	mu.Lock()
+	defer mu.Unlock()
 
-	return tickers, nil
+	for ticker, name := range names {
+		if _, ok := symbols[ticker]; !ok {
+			symbols[ticker] = Record{Ticker: ticker, Name: name}
+		}
+	}
 }
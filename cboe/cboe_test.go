@@ -0,0 +1,96 @@
+package cboe
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	body := "\xef\xbb\xbf\"Company Name\",\"Product Type\",\"List Date\",\"Stock Symbol\"\r\n" +
+		"\"Coca-Cola Co, The\",\"Equity\",\"2010-06-04\",\"KO\"\r\n" +
+		"\"ProShares UltraPro Short QQQ\",\"ETF\",\"2011-01-07\",\"sqqq\"\r\n" +
+		"\"Blank\",\"Equity\",\"2011-01-07\",\"\"\r\n"
+
+	records, err := parse([]byte(body))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %v", records)
+	}
+
+	ko := records[0]
+	if ko.Ticker != "KO" || ko.Name != "Coca-Cola Co, The" || ko.ProductType != "Equity" || ko.ListDate != "2010-06-04" {
+		t.Errorf("Unexpected record %+v", ko)
+	}
+	if ko.Fields["Company Name"] != "Coca-Cola Co, The" {
+		t.Errorf("Unexpected fields %v", ko.Fields)
+	}
+	if ko.IsETF() || !ko.IsEquity() {
+		t.Errorf("Expected %s to be an equity", ko.Ticker)
+	}
+
+	sqqq := records[1]
+	if sqqq.Ticker != "SQQQ" || !sqqq.IsETF() || sqqq.IsEquity() {
+		t.Errorf("Expected %+v to be an ETF", sqqq)
+	}
+
+	etfs := Tickers(records, Record.IsETF)
+	if !slices.Equal(etfs, []string{"SQQQ"}) {
+		t.Errorf("Expected [SQQQ], got %v", etfs)
+	}
+}
+
+func TestParseNameOnly(t *testing.T) {
+	body := "Company Name,Symbol\nSPDR S&P 500 ETF Trust,SPY\nApple Inc,AAPL\n"
+
+	records, err := parse([]byte(body))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if !records[0].IsETF() || records[1].IsETF() {
+		t.Errorf("Expected only SPY to be an ETF, got %+v", records)
+	}
+}
+
+func TestRemember(t *testing.T) {
+	// Do not download the directory
+	fetched = true
+	t.Cleanup(func() { fetched = false })
+
+	Remember(map[string]string{"TQQQ": "ProShares UltraPro QQQ"})
+
+	r, ok := Lookup("TQQQ")
+	if !ok || r.Name != "ProShares UltraPro QQQ" {
+		t.Errorf("Expected TQQQ's name, got %+v %t", r, ok)
+	}
+	if _, ok := Lookup("NONE"); ok {
+		t.Errorf("Expected no record for NONE")
+	}
+
+	names := Names([]string{"TQQQ", "NONE"})
+	if len(names) != 1 || names["TQQQ"] != "ProShares UltraPro QQQ" {
+		t.Errorf("Unexpected names %v", names)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	testCases := []string{
+		"",
+		"  \n",
+		"<!DOCTYPE html><html><body>Access Denied</body></html>",
+		"Company Name,Product Type\nCoca-Cola,Equity\n",
+		"Company Name,Symbol\n",
+		"Company Name,Symbol\nCoca-Cola,KO,extra\n",
+		"Company Name,Symbol\n\"Coca-Cola,KO\n",
+	}
+
+	for _, testCase := range testCases {
+		_, err := parse([]byte(testCase))
+		if err == nil {
+			t.Errorf("ERROR: For %q expected an error", testCase)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package correlation
+
+import (
+	"math"
+	"sort"
+
+	"github.com/erikbryant/options/security"
+)
+
+// minOverlap is the fewest common days of returns we will correlate over
+const minOverlap = 20
+
+// returns returns the daily log returns of the last 'days' closes, keyed by date
+func returns(candles []security.DayRange, days int) map[string]float64 {
+	result := map[string]float64{}
+
+	start := max(1, len(candles)-days)
+	for i := start; i < len(candles); i++ {
+		if candles[i-1].Close <= 0 || candles[i].Close <= 0 {
+			continue
+		}
+		result[candles[i].Date] = math.Log(candles[i].Close / candles[i-1].Close)
+	}
+
+	return result
+}
+
+// Pearson returns the correlation of the two series over the dates they have
+// in common, and whether there were enough of them to say
+func Pearson(a, b map[string]float64) (float64, bool) {
+	var x, y []float64
+	for day, r := range a {
+		if s, ok := b[day]; ok {
+			x = append(x, r)
+			y = append(y, s)
+		}
+	}
+
+	n := float64(len(x))
+	if len(x) < minOverlap {
+		return 0, false
+	}
+
+	meanX, meanY := 0.0, 0.0
+	for i := range x {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX /= n
+	meanY /= n
+
+	cov, varX, varY := 0.0, 0.0, 0.0
+	for i := range x {
+		cov += (x[i] - meanX) * (y[i] - meanY)
+		varX += (x[i] - meanX) * (x[i] - meanX)
+		varY += (y[i] - meanY) * (y[i] - meanY)
+	}
+
+	if varX == 0 || varY == 0 {
+		return 0, false
+	}
+
+	return cov / math.Sqrt(varX*varY), true
+}
+
+// find returns the root of i's cluster
+func find(parent []int, i int) int {
+	for parent[i] != i {
+		parent[i] = parent[parent[i]]
+		i = parent[i]
+	}
+	return i
+}
+
+// Cluster groups the securities whose daily returns over the last 'days'
+// correlate at or above threshold (directly, or through each other) and sets
+// each security's Cluster. Clusters are numbered from 1 in ticker order.
+func Cluster(securities []*security.Security, days int, threshold float64) {
+	series := make([]map[string]float64, len(securities))
+	for i, sec := range securities {
+		series[i] = returns(sec.Candles, days)
+	}
+
+	parent := make([]int, len(securities))
+	for i := range parent {
+		parent[i] = i
+	}
+
+	for i := range securities {
+		for j := i + 1; j < len(securities); j++ {
+			r, ok := Pearson(series[i], series[j])
+			if !ok || r < threshold {
+				continue
+			}
+			parent[find(parent, i)] = find(parent, j)
+		}
+	}
+
+	// Number the clusters in ticker order so runs are reproducible
+	order := make([]int, len(securities))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return securities[order[a]].Ticker < securities[order[b]].Ticker
+	})
+
+	ids := map[int]int{}
+	for _, i := range order {
+		root := find(parent, i)
+		if _, ok := ids[root]; !ok {
+			ids[root] = len(ids) + 1
+		}
+		securities[i].Cluster = ids[root]
+	}
+}
+
+// Underlyings returns each distinct security in the selections
+func Underlyings(sheets ...[]security.Selection) []*security.Security {
+	seen := map[*security.Security]bool{}
+	result := []*security.Security{}
+	for _, sheet := range sheets {
+		for _, sel := range sheet {
+			if seen[sel.Security] {
+				continue
+			}
+			seen[sel.Security] = true
+			result = append(result, sel.Security)
+		}
+	}
+	return result
+}
+
+// Diversify returns the best selection (highest net annualized return) from
+// each cluster, in their original order
+func Diversify(selections []security.Selection) []security.Selection {
+	best := map[int]int{}
+	for i, sel := range selections {
+		cluster := sel.Security.Cluster
+		j, ok := best[cluster]
+		if !ok || sel.Contract.NetAnnualized > selections[j].Contract.NetAnnualized {
+			best[cluster] = i
+		}
+	}
+
+	result := []security.Selection{}
+	for i, sel := range selections {
+		if best[sel.Security.Cluster] == i {
+			result = append(result, sel)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,75 @@
+package correlation
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/erikbryant/options/security"
+)
+
+// candles returns a price history whose daily returns follow f
+func candles(days int, f func(day int) float64) []security.DayRange {
+	result := []security.DayRange{}
+	price := 100.0
+	for day := 0; day <= days; day++ {
+		result = append(result, security.DayRange{Date: fmt.Sprintf("d%03d", day), Close: price})
+		price *= math.Exp(f(day))
+	}
+	return result
+}
+
+func TestPearson(t *testing.T) {
+	up := func(day int) float64 { return 0.01 * math.Sin(float64(day)) }
+	down := func(day int) float64 { return -0.01 * math.Sin(float64(day)) }
+
+	a := returns(candles(60, up), 60)
+	b := returns(candles(60, up), 60)
+	c := returns(candles(60, down), 60)
+
+	r, ok := Pearson(a, b)
+	if !ok || math.Abs(r-1) > 0.000001 {
+		t.Errorf("Expected 1, got %f %v", r, ok)
+	}
+
+	r, ok = Pearson(a, c)
+	if !ok || math.Abs(r+1) > 0.000001 {
+		t.Errorf("Expected -1, got %f %v", r, ok)
+	}
+
+	// Not enough overlap
+	_, ok = Pearson(returns(candles(10, up), 10), a)
+	if ok {
+		t.Errorf("Expected too little overlap")
+	}
+}
+
+func TestClusterDiversify(t *testing.T) {
+	wave := func(day int) float64 { return 0.01 * math.Sin(float64(day)) }
+	other := func(day int) float64 { return 0.01 * math.Cos(float64(day)*1.7) }
+
+	a := security.Security{Ticker: "A", Candles: candles(60, wave)}
+	b := security.Security{Ticker: "B", Candles: candles(60, wave)}
+	c := security.Security{Ticker: "C", Candles: candles(60, other)}
+
+	puts := []security.Selection{
+		{Security: &c, Contract: security.Contract{NetAnnualized: 5}},
+		{Security: &a, Contract: security.Contract{NetAnnualized: 10}},
+		{Security: &b, Contract: security.Contract{NetAnnualized: 20}},
+		{Security: &a, Contract: security.Contract{NetAnnualized: 15}},
+	}
+
+	Cluster(Underlyings(puts), 60, 0.7)
+
+	if a.Cluster != 1 || b.Cluster != 1 || c.Cluster != 2 {
+		t.Errorf("Expected clusters 1, 1, 2, got %d, %d, %d", a.Cluster, b.Cluster, c.Cluster)
+	}
+
+	diversified := Diversify(puts)
+	if len(diversified) != 2 {
+		t.Fatalf("Expected 2 selections, got %d", len(diversified))
+	}
+	if diversified[0].Security.Ticker != "C" || diversified[1].Security.Ticker != "B" {
+		t.Errorf("Expected C then B, got %s then %s", diversified[0].Security.Ticker, diversified[1].Security.Ticker)
+	}
+}
@@ -16,10 +16,14 @@ func GetFile(file string) ([]string, error) {
 	lines := strings.Split(string(contents), "\n")
 
 	// Strip trailing blank lines
-	for lines[len(lines)-1] == "" {
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
 		lines = lines[:len(lines)-1]
 	}
 
+	if len(lines) == 0 {
+		return []string{}, nil
+	}
+
 	// Skip the header line
 	return lines[1:], nil
 }
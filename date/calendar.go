@@ -0,0 +1,273 @@
+package date
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// NYSE trading hours, in Eastern time.
+const (
+	closeHour      = 16
+	earlyCloseHour = 13
+)
+
+// specialClosures are one-off full day closures that no rule predicts.
+var specialClosures = map[string]string{
+	"2012-10-29": "Hurricane Sandy",
+	"2012-10-30": "Hurricane Sandy",
+	"2018-12-05": "National Day of Mourning for George H.W. Bush",
+	"2025-01-09": "National Day of Mourning for Jimmy Carter",
+}
+
+// calendar holds the market closures for a single year.
+type calendar struct {
+	holidays    map[string]string
+	earlyCloses map[string]string
+}
+
+var (
+	calendarsMu sync.Mutex
+	calendars   = map[int]calendar{}
+)
+
+// Eastern returns the time zone the market runs on.
+func Eastern() *time.Location {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// midnight returns midnight ET of the (ET) day t falls on.
+func midnight(t time.Time) time.Time {
+	et := t.In(Eastern())
+	year, month, day := et.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, et.Location())
+}
+
+// Format returns t's (ET) day in YYYY-MM-DD form.
+func Format(t time.Time) string {
+	return midnight(t).Format("2006-01-02")
+}
+
+// ParseDay returns midnight ET of the given YYYY-MM-DD day.
+func ParseDay(day string) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", day, Eastern())
+}
+
+// nthWeekday returns the nth (1-based) given weekday of the month. A
+// negative n counts back from the end of the month.
+func nthWeekday(year int, month time.Month, wDay time.Weekday, n int) time.Time {
+	if n < 0 {
+		t := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+		for t.Weekday() != wDay {
+			t = t.AddDate(0, 0, -1)
+		}
+		return t.AddDate(0, 0, 7*(n+1))
+	}
+
+	t := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	for t.Weekday() != wDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t.AddDate(0, 0, 7*(n-1))
+}
+
+// easter returns Easter Sunday of the given year (Anonymous Gregorian algorithm).
+func easter(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// observed returns the weekday a fixed-date holiday is observed on.
+func observed(t time.Time) time.Time {
+	switch t.Weekday() {
+	case time.Saturday:
+		return t.AddDate(0, 0, -1)
+	case time.Sunday:
+		return t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// buildCalendar computes the NYSE holidays and early closes for a year.
+func buildCalendar(year int) calendar {
+	cal := calendar{
+		holidays:    map[string]string{},
+		earlyCloses: map[string]string{},
+	}
+
+	add := func(t time.Time, name string) {
+		cal.holidays[t.Format("2006-01-02")] = name
+	}
+
+	// NYSE does not close on the preceding Friday when New Year's Day falls
+	// on a Saturday.
+	newYear := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if newYear.Weekday() != time.Saturday {
+		add(observed(newYear), "New Year's Day")
+	}
+	add(nthWeekday(year, time.January, time.Monday, 3), "Martin Luther King, Jr. Day")
+	add(nthWeekday(year, time.February, time.Monday, 3), "Washington's Birthday")
+	add(easter(year).AddDate(0, 0, -2), "Good Friday")
+	add(nthWeekday(year, time.May, time.Monday, -1), "Memorial Day")
+	if year >= 2022 {
+		add(observed(time.Date(year, time.June, 19, 0, 0, 0, 0, time.UTC)), "Juneteenth")
+	}
+	add(observed(time.Date(year, time.July, 4, 0, 0, 0, 0, time.UTC)), "Independence Day")
+	add(nthWeekday(year, time.September, time.Monday, 1), "Labor Day")
+	thanksgiving := nthWeekday(year, time.November, time.Thursday, 4)
+	add(thanksgiving, "Thanksgiving Day")
+	add(observed(time.Date(year, time.December, 25, 0, 0, 0, 0, time.UTC)), "Christmas Day")
+
+	for day, name := range specialClosures {
+		t, _ := time.Parse("2006-01-02", day)
+		if t.Year() == year {
+			cal.holidays[day] = name
+		}
+	}
+
+	early := func(t time.Time, name string) {
+		day := t.Format("2006-01-02")
+		if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+			return
+		}
+		if _, ok := cal.holidays[day]; ok {
+			return
+		}
+		cal.earlyCloses[day] = name
+	}
+
+	early(time.Date(year, time.July, 3, 0, 0, 0, 0, time.UTC), "Day before Independence Day")
+	early(thanksgiving.AddDate(0, 0, 1), "Day after Thanksgiving")
+	early(time.Date(year, time.December, 24, 0, 0, 0, 0, time.UTC), "Christmas Eve")
+
+	return cal
+}
+
+// calendarFor returns the (memoized) calendar for the given year.
+func calendarFor(year int) calendar {
+	calendarsMu.Lock()
+	defer calendarsMu.Unlock()
+
+	cal, ok := calendars[year]
+	if !ok {
+		cal = buildCalendar(year)
+		calendars[year] = cal
+	}
+	return cal
+}
+
+// Holiday returns the name of the market holiday on t's (ET) day, if any.
+func Holiday(t time.Time) (string, bool) {
+	day := midnight(t)
+	name, ok := calendarFor(day.Year()).holidays[day.Format("2006-01-02")]
+	return name, ok
+}
+
+// IsEarlyClose returns whether the market closes early on t's (ET) day.
+func IsEarlyClose(t time.Time) bool {
+	day := midnight(t)
+	_, ok := calendarFor(day.Year()).earlyCloses[day.Format("2006-01-02")]
+	return ok
+}
+
+// IsTradingDay returns whether the market is open on t's (ET) day.
+func IsTradingDay(t time.Time) bool {
+	weekday := midnight(t).Weekday()
+	if weekday == time.Saturday || weekday == time.Sunday {
+		return false
+	}
+	_, holiday := Holiday(t)
+	return !holiday
+}
+
+// CloseTime returns the time the market closes on t's (ET) day.
+func CloseTime(t time.Time) time.Time {
+	day := midnight(t)
+	hour := closeHour
+	if IsEarlyClose(day) {
+		hour = earlyCloseHour
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, 0, 0, 0, day.Location())
+}
+
+// PreviousTradingDay returns midnight ET of the last trading day before t's day.
+func PreviousTradingDay(t time.Time) time.Time {
+	day := midnight(t).AddDate(0, 0, -1)
+	for !IsTradingDay(day) {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day
+}
+
+// NextTradingDay returns midnight ET of the first trading day after t's day.
+func NextTradingDay(t time.Time) time.Time {
+	day := midnight(t).AddDate(0, 0, 1)
+	for !IsTradingDay(day) {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// OnOrBefore returns midnight ET of the last trading day on or before t's day.
+func OnOrBefore(t time.Time) time.Time {
+	if IsTradingDay(t) {
+		return midnight(t)
+	}
+	return PreviousTradingDay(t)
+}
+
+// OnOrAfter returns midnight ET of the first trading day on or after t's day.
+func OnOrAfter(t time.Time) time.Time {
+	if IsTradingDay(t) {
+		return midnight(t)
+	}
+	return NextTradingDay(t)
+}
+
+// TradingDaysBetween returns the number of trading days after start's day, up
+// to and including end's day. It is zero if end is not after start.
+func TradingDaysBetween(start, end time.Time) int {
+	count := 0
+	last := midnight(end)
+	for day := midnight(start).AddDate(0, 0, 1); !day.After(last); day = day.AddDate(0, 0, 1) {
+		if IsTradingDay(day) {
+			count++
+		}
+	}
+	return count
+}
+
+// DaysBetween returns the number of calendar days from start's (ET) day to
+// end's (ET) day.
+func DaysBetween(start, end time.Time) int {
+	// Round, since a day is not always 24 hours long across DST changes
+	return int(math.Round(midnight(end).Sub(midnight(start)).Hours() / 24))
+}
+
+// LastClose returns the time of the most recent market close at or before t.
+func LastClose(t time.Time) time.Time {
+	if IsTradingDay(t) {
+		closeTime := CloseTime(t)
+		if !t.Before(closeTime) {
+			return closeTime
+		}
+	}
+	return CloseTime(PreviousTradingDay(t))
+}
@@ -0,0 +1,220 @@
+package date
+
+import (
+	"testing"
+	"time"
+)
+
+// day returns midnight ET of the given YYYY-MM-DD day.
+func day(t *testing.T, s string) time.Time {
+	d, err := ParseDay(s)
+	if err != nil {
+		t.Fatalf("unable to parse %s %s", s, err)
+	}
+	return d
+}
+
+func TestHoliday(t *testing.T) {
+	testCases := []struct {
+		day      string
+		expected bool
+	}{
+		// 2024
+		{"2024-01-01", true},
+		{"2024-01-15", true},
+		{"2024-02-19", true},
+		{"2024-03-29", true},
+		{"2024-05-27", true},
+		{"2024-06-19", true},
+		{"2024-07-04", true},
+		{"2024-09-02", true},
+		{"2024-11-28", true},
+		{"2024-12-25", true},
+		{"2024-07-03", false},
+		{"2024-11-29", false},
+		// New Year's Day on a Saturday is not observed
+		{"2021-12-31", false},
+		// Special closure
+		{"2025-01-09", true},
+		// Observed on the Friday before
+		{"2026-07-03", true},
+		{"2027-06-18", true},
+		{"2027-12-24", true},
+		// Observed on the Monday after
+		{"2022-06-20", true},
+		{"2022-12-26", true},
+		// Juneteenth was not a market holiday before 2022
+		{"2021-06-18", false},
+	}
+
+	for _, testCase := range testCases {
+		_, answer := Holiday(day(t, testCase.day))
+		if answer != testCase.expected {
+			t.Errorf("For %s expected %v, got %v", testCase.day, testCase.expected, answer)
+		}
+	}
+}
+
+func TestIsEarlyClose(t *testing.T) {
+	testCases := []struct {
+		day      string
+		expected bool
+	}{
+		{"2024-07-03", true},
+		{"2024-11-29", true},
+		{"2024-12-24", true},
+		{"2024-12-23", false},
+		// July 3rd is the observed holiday
+		{"2026-07-03", false},
+		// Christmas Eve on a weekend
+		{"2023-12-24", false},
+		// Christmas Eve is the observed holiday
+		{"2021-12-24", false},
+	}
+
+	for _, testCase := range testCases {
+		answer := IsEarlyClose(day(t, testCase.day))
+		if answer != testCase.expected {
+			t.Errorf("For %s expected %v, got %v", testCase.day, testCase.expected, answer)
+		}
+	}
+}
+
+func TestCloseTime(t *testing.T) {
+	testCases := []struct {
+		day      string
+		expected time.Time
+	}{
+		{"2024-07-02", time.Date(2024, time.July, 2, 20, 0, 0, 0, time.UTC)},
+		{"2024-07-03", time.Date(2024, time.July, 3, 17, 0, 0, 0, time.UTC)},
+		{"2024-11-29", time.Date(2024, time.November, 29, 18, 0, 0, 0, time.UTC)},
+	}
+
+	for _, testCase := range testCases {
+		answer := CloseTime(day(t, testCase.day))
+		if !answer.Equal(testCase.expected) {
+			t.Errorf("For %s expected %v, got %v", testCase.day, testCase.expected, answer)
+		}
+	}
+}
+
+func TestPreviousNextTradingDay(t *testing.T) {
+	testCases := []struct {
+		day      string
+		previous string
+		next     string
+	}{
+		// Around Labor Day
+		{"2024-09-03", "2024-08-30", "2024-09-04"},
+		{"2024-08-30", "2024-08-29", "2024-09-03"},
+		// Over a weekend
+		{"2024-09-07", "2024-09-06", "2024-09-09"},
+		// Good Friday
+		{"2024-03-28", "2024-03-27", "2024-04-01"},
+	}
+
+	for _, testCase := range testCases {
+		previous := PreviousTradingDay(day(t, testCase.day)).Format("2006-01-02")
+		if previous != testCase.previous {
+			t.Errorf("For %s expected previous %s, got %s", testCase.day, testCase.previous, previous)
+		}
+		next := NextTradingDay(day(t, testCase.day)).Format("2006-01-02")
+		if next != testCase.next {
+			t.Errorf("For %s expected next %s, got %s", testCase.day, testCase.next, next)
+		}
+	}
+}
+
+func TestTradingDaysBetween(t *testing.T) {
+	testCases := []struct {
+		start    string
+		end      string
+		expected int
+	}{
+		{"2024-09-03", "2024-09-03", 0},
+		{"2024-09-04", "2024-09-03", 0},
+		{"2024-09-03", "2024-09-04", 1},
+		// Friday to Tuesday after Labor Day
+		{"2024-08-30", "2024-09-03", 1},
+		// A full week
+		{"2024-09-06", "2024-09-13", 5},
+		// Thanksgiving week
+		{"2024-11-22", "2024-11-29", 4},
+	}
+
+	for _, testCase := range testCases {
+		answer := TradingDaysBetween(day(t, testCase.start), day(t, testCase.end))
+		if answer != testCase.expected {
+			t.Errorf("For %s - %s expected %d, got %d", testCase.start, testCase.end, testCase.expected, answer)
+		}
+	}
+}
+
+func TestDaysBetween(t *testing.T) {
+	testCases := []struct {
+		start    time.Time
+		end      time.Time
+		expected int
+	}{
+		{time.Date(2024, time.March, 8, 12, 0, 0, 0, time.UTC), time.Date(2024, time.March, 8, 23, 0, 0, 0, time.UTC), 0},
+		// Across the start of DST
+		{time.Date(2024, time.March, 8, 12, 0, 0, 0, time.UTC), time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC), 7},
+		// 2:00 UTC is still the previous day in ET
+		{time.Date(2024, time.March, 8, 12, 0, 0, 0, time.UTC), time.Date(2024, time.March, 9, 2, 0, 0, 0, time.UTC), 0},
+		{time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC), time.Date(2024, time.March, 8, 12, 0, 0, 0, time.UTC), -7},
+	}
+
+	for _, testCase := range testCases {
+		answer := DaysBetween(testCase.start, testCase.end)
+		if answer != testCase.expected {
+			t.Errorf("For %v - %v expected %d, got %d", testCase.start, testCase.end, testCase.expected, answer)
+		}
+	}
+}
+
+func TestLastClose(t *testing.T) {
+	testCases := []struct {
+		t        time.Time
+		expected time.Time
+	}{
+		// Tuesday after Labor Day, before the close: Friday's close
+		{time.Date(2024, time.September, 3, 14, 0, 0, 0, time.UTC), time.Date(2024, time.August, 30, 20, 0, 0, 0, time.UTC)},
+		// Tuesday after Labor Day, after the close
+		{time.Date(2024, time.September, 3, 20, 0, 0, 0, time.UTC), time.Date(2024, time.September, 3, 20, 0, 0, 0, time.UTC)},
+		// Day after Thanksgiving, after the early close
+		{time.Date(2024, time.November, 29, 18, 30, 0, 0, time.UTC), time.Date(2024, time.November, 29, 18, 0, 0, 0, time.UTC)},
+		// Thanksgiving Day
+		{time.Date(2024, time.November, 28, 18, 30, 0, 0, time.UTC), time.Date(2024, time.November, 27, 21, 0, 0, 0, time.UTC)},
+	}
+
+	for _, testCase := range testCases {
+		answer := LastClose(testCase.t)
+		if !answer.Equal(testCase.expected) {
+			t.Errorf("For %v expected %v, got %v", testCase.t, testCase.expected, answer)
+		}
+	}
+}
+
+func TestPreviousWeek(t *testing.T) {
+	testCases := []struct {
+		t     time.Time
+		start string
+		end   string
+	}{
+		// Saturday after a normal week
+		{time.Date(2024, time.September, 14, 12, 0, 0, 0, time.UTC), "2024-09-09", "2024-09-13"},
+		// Saturday after Labor Day week
+		{time.Date(2024, time.September, 7, 12, 0, 0, 0, time.UTC), "2024-09-03", "2024-09-06"},
+		// Saturday after a Good Friday week
+		{time.Date(2024, time.March, 30, 12, 0, 0, 0, time.UTC), "2024-03-25", "2024-03-28"},
+		// Midweek uses the week before
+		{time.Date(2024, time.September, 11, 12, 0, 0, 0, time.UTC), "2024-09-03", "2024-09-06"},
+	}
+
+	for _, testCase := range testCases {
+		start, end := PreviousWeek(testCase.t)
+		if start != testCase.start || end != testCase.end {
+			t.Errorf("For %v expected %s - %s, got %s - %s", testCase.t, testCase.start, testCase.end, start, end)
+		}
+	}
+}
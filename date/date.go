@@ -4,53 +4,22 @@ import (
 	"time"
 )
 
-// timeSinceMidnight returns the number of seconds that have elapsed since midnight of the given day.
-func timeSinceMidnight(t time.Time) time.Duration {
-	year, month, day := t.Date()
-	t2 := time.Date(year, month, day, 0, 0, 0, 0, t.Location())
-	return t.Sub(t2)
-}
-
-// TimeSinceClose returns the time between the last market close and the given time (ignoring holidays).
+// TimeSinceClose returns the time between the last market close and the given time.
 func TimeSinceClose(t time.Time) time.Duration {
-	// The market runs on Eastern time.
-	eastern, _ := time.LoadLocation("America/New_York")
-	et := t.In(eastern)
-
-	hour := et.Hour()
-	weekday := et.Weekday()
-
-	if weekday == time.Monday && hour < 16 {
-		// Use Friday's close.
-		// 4pm - midnight == 8 hours.
-		return 8*time.Hour + 24*time.Hour + 24*time.Hour + timeSinceMidnight(et)
-	}
-
-	if weekday == time.Sunday {
-		// Use Friday's close.
-		return 8*time.Hour + 24*time.Hour + timeSinceMidnight(et)
-	}
-
-	if weekday == time.Saturday || hour < 16 {
-		// Use yesterday's close.
-		return 8*time.Hour + timeSinceMidnight(et)
-	}
+	return t.Sub(LastClose(t))
+}
 
-	if hour >= 16 {
-		// The market has closed today. Use 4pm today.
-		return timeSinceMidnight(et) - 16*time.Hour
+// PreviousWeek returns the first and last trading days of the most recent
+// Monday through Friday week whose Friday is on or before t.
+func PreviousWeek(t time.Time) (string, string) {
+	friday := midnight(t)
+	for friday.Weekday() != time.Friday {
+		friday = friday.AddDate(0, 0, -1)
 	}
+	monday := friday.AddDate(0, 0, -4)
 
-	// TODO: Check for market holiday.
+	start := OnOrAfter(monday)
+	end := OnOrBefore(friday)
 
-	return 0
-}
-
-// Previous returns the date of the previous given weekday
-func Previous(wDay time.Weekday) string {
-	day := time.Now()
-	for day.Weekday() != wDay {
-		day = day.AddDate(0, 0, -1)
-	}
-	return day.Format("2006-01-02")
+	return start.Format("2006-01-02"), end.Format("2006-01-02")
 }
@@ -5,26 +5,6 @@ import (
 	"time"
 )
 
-func TestTimeSinceMidnight(t *testing.T) {
-	testCases := []struct {
-		t        time.Time
-		expected time.Duration
-	}{
-		{time.Time{}, 0},
-		{time.Date(2021, time.Month(2), 21, 0, 0, 0, 0, time.UTC), 0},
-		{time.Date(2021, time.Month(2), 21, 0, 0, 1, 0, time.UTC), 1 * time.Second},
-		{time.Date(2022, time.Month(1), 30, 23, 0, 0, 0, time.UTC), 23 * time.Hour},
-		{time.Date(2022, time.Month(1), 30, 23, 59, 59, 0, time.UTC), 23*time.Hour + 59*time.Minute + 59*time.Second},
-	}
-
-	for _, testCase := range testCases {
-		answer := timeSinceMidnight(testCase.t)
-		if answer != testCase.expected {
-			t.Errorf("For %v expected %v, got %v", testCase.t, testCase.expected, answer)
-		}
-	}
-}
-
 func TestTimeSinceClose(t *testing.T) {
 	testCases := []struct {
 		t        time.Time
@@ -71,6 +51,12 @@ func TestTimeSinceClose(t *testing.T) {
 		{time.Date(2022, time.Month(1), 4, 21, 0, 1, 0, time.UTC), 1 * time.Second},
 		//   Wednesday 4:59 UTC is Tuesday 23:59 Eastern
 		{time.Date(2022, time.Month(1), 5, 4, 59, 0, 0, time.UTC), 7*time.Hour + 59*time.Minute},
+
+		// Market holiday
+		//   Tuesday after Labor Day 13:00 UTC is 9:00 Eastern, Friday's close was used
+		{time.Date(2022, time.Month(9), 6, 13, 0, 0, 0, time.UTC), 89 * time.Hour},
+		//   Day after Thanksgiving 19:00 UTC is 14:00 Eastern, after the early close
+		{time.Date(2022, time.Month(11), 25, 19, 0, 0, 0, time.UTC), 1 * time.Hour},
 	}
 
 	for _, testCase := range testCases {
@@ -0,0 +1,81 @@
+package dividends
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/erikbryant/options/csv"
+)
+
+// Dividend is an upcoming dividend payment
+type Dividend struct {
+	ExDate string  // YYYY-MM-DD
+	Amount float64 // per share
+}
+
+// calendar is the locally maintained dividend calendar, by ticker
+var calendar = map[string]Dividend{}
+
+// parse converts CSV rows of ticker,exDate,amount into a dividend calendar,
+// keeping the earliest ex-dividend date on or after today for each ticker
+func parse(lines []string, today string) (map[string]Dividend, error) {
+	dividends := map[string]Dividend{}
+
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		cols := strings.Split(line, ",")
+		if len(cols) != 3 {
+			return nil, fmt.Errorf("line %d: expected ticker,exDate,amount, got '%s'", i+1, line)
+		}
+
+		ticker := strings.TrimSpace(cols[0])
+		exDate := strings.TrimSpace(cols[1])
+		amount, err := strconv.ParseFloat(strings.TrimSpace(cols[2]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: unable to parse amount %s", i+1, err)
+		}
+
+		if exDate < today {
+			continue
+		}
+		if d, ok := dividends[ticker]; ok && d.ExDate <= exDate {
+			continue
+		}
+		dividends[ticker] = Dividend{ExDate: exDate, Amount: amount}
+	}
+
+	return dividends, nil
+}
+
+// Init loads the local dividend calendar. A missing file is not an error;
+// there are simply no local entries.
+func Init(file, today string) error {
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		calendar = map[string]Dividend{}
+		return nil
+	}
+
+	lines, err := csv.GetFile(file)
+	if err != nil {
+		return err
+	}
+
+	calendar, err = parse(lines, today)
+	if err != nil {
+		return fmt.Errorf("unable to parse %s %s", file, err)
+	}
+
+	return nil
+}
+
+// Next returns the next dividend for ticker from the local calendar, if any
+func Next(ticker string) (Dividend, bool) {
+	d, ok := calendar[ticker]
+	return d, ok
+}
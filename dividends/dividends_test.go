@@ -0,0 +1,51 @@
+package dividends
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	lines := []string{
+		"# Comments and blank lines are ignored",
+		"",
+		"KO,2024-09-13,0.485",
+		"KO,2024-11-29,0.485",
+		"T,2024-07-10,0.2775",
+		"T,2024-10-10,0.2775",
+		" PFE , 2024-11-08 , 0.42 ",
+	}
+
+	dividends, err := parse(lines, "2024-09-01")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	expected := map[string]Dividend{
+		"KO":  {ExDate: "2024-09-13", Amount: 0.485},
+		"T":   {ExDate: "2024-10-10", Amount: 0.2775},
+		"PFE": {ExDate: "2024-11-08", Amount: 0.42},
+	}
+
+	if len(dividends) != len(expected) {
+		t.Errorf("Expected %v, got %v", expected, dividends)
+	}
+	for ticker, d := range expected {
+		if dividends[ticker] != d {
+			t.Errorf("For %s expected %v, got %v", ticker, d, dividends[ticker])
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	testCases := [][]string{
+		{"KO,2024-09-13"},
+		{"KO,2024-09-13,abc"},
+	}
+
+	for _, testCase := range testCases {
+		_, err := parse(testCase, "2024-09-01")
+		if err == nil {
+			t.Errorf("For %v expected an error", testCase)
+		}
+	}
+}
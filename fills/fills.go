@@ -0,0 +1,72 @@
+package fills
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/erikbryant/options/csv"
+)
+
+// learn returns the average fraction of the bid-ask spread above the bid we
+// were filled at, and how many fills it is based on. Each line is
+// date,ticker,expiration,strike,bid,ask,fill.
+func learn(lines []string) (float64, int, error) {
+	total := 0.0
+	samples := 0
+
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		cols := strings.Split(line, ",")
+		if len(cols) != 7 {
+			return 0, 0, fmt.Errorf("line %d: expected date,ticker,expiration,strike,bid,ask,fill, got '%s'", i+1, line)
+		}
+
+		var vals [3]float64
+		for j, col := range cols[4:] {
+			v, err := strconv.ParseFloat(strings.TrimSpace(col), 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("line %d: unable to parse %s", i+1, err)
+			}
+			vals[j] = v
+		}
+		bid, ask, fill := vals[0], vals[1], vals[2]
+
+		// A locked market tells us nothing about where in the spread we fill
+		if ask <= bid {
+			continue
+		}
+
+		fraction := (fill - bid) / (ask - bid)
+		fraction = min(1, max(0, fraction))
+
+		total += fraction
+		samples++
+	}
+
+	if samples == 0 {
+		return 0, 0, nil
+	}
+
+	return total / float64(samples), samples, nil
+}
+
+// Learn returns the average fraction of the bid-ask spread above the bid our
+// past fills (recorded in file) were at, and how many fills that is based on
+func Learn(file string) (float64, int, error) {
+	lines, err := csv.GetFile(file)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	fraction, samples, err := learn(lines)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to parse %s %s", file, err)
+	}
+
+	return fraction, samples, nil
+}
@@ -0,0 +1,33 @@
+package fills
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLearn(t *testing.T) {
+	lines := []string{
+		"2024-09-06,KO,2024-09-13,70,0.40,0.60,0.50",
+		"2024-09-06,T,2024-09-13,20,0.10,0.20,0.10",
+		// Locked market; ignored
+		"2024-09-06,F,2024-09-13,10,0.10,0.10,0.10",
+		// Filled outside the spread; clamped
+		"2024-09-06,PFE,2024-09-13,30,0.20,0.30,0.35",
+	}
+
+	fraction, samples, err := learn(lines)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if samples != 3 {
+		t.Errorf("Expected 3 samples, got %d", samples)
+	}
+	if math.Abs(fraction-0.5) > 0.000001 {
+		t.Errorf("Expected 0.5, got %f", fraction)
+	}
+
+	_, _, err = learn([]string{"2024-09-06,KO,2024-09-13,70,0.40,0.60"})
+	if err == nil {
+		t.Errorf("Expected an error for a short line")
+	}
+}
@@ -16,24 +16,29 @@ import (
 )
 
 var (
-	cipherAuthToken = "GDrwdFOt/zTS12HUuCYE82Xjdzoa5EYOT9e377XNYc0w2St/CNQ0M/jOZorYzU1G"
-	authToken       = ""
-	earnings        map[string]string
+	cipherAuthToken  = "GDrwdFOt/zTS12HUuCYE82Xjdzoa5EYOT9e377XNYc0w2St/CNQ0M/jOZorYzU1G"
+	authToken        = ""
+	earnings         map[string]string
+	latestExpiration string
 )
 
 // Init initializes the internal state of the package
-func Init(passPhrase, latestExpiration string) {
+func Init(passPhrase, expiration string) error {
 	var err error
 
 	authToken, err = aes.Decrypt(cipherAuthToken, passPhrase)
 	if err != nil {
-		panic("Incorrect passphrase for FinnHub")
+		return fmt.Errorf("incorrect passphrase for FinnHub %s", err)
 	}
 
-	earnings, err = earningDates(latestExpiration)
+	earnings, err = earningDates(expiration)
 	if err != nil {
-		panic("Unable to get earnings dates")
+		return fmt.Errorf("unable to get earnings dates %s", err)
 	}
+
+	latestExpiration = expiration
+
+	return nil
 }
 
 func Earnings(symbol string) string {
@@ -81,14 +86,22 @@ func webRequest(url string) (map[string]interface{}, bool, error) {
 		return nil, false, err
 	}
 
-	var jsonObject map[string]interface{}
+	var jsonObject interface{}
 
 	err = json.Unmarshal(contents, &jsonObject)
 	if err != nil {
 		return nil, false, fmt.Errorf("unable to unmarshal json %s", err)
 	}
 
-	return jsonObject, false, nil
+	switch j := jsonObject.(type) {
+	case map[string]interface{}:
+		return j, false, nil
+	case []interface{}:
+		// Some endpoints return a bare list; wrap it so it can be cached like the rest
+		return map[string]interface{}{"data": j}, false, nil
+	}
+
+	return nil, false, fmt.Errorf("unexpected json type %T", jsonObject)
 }
 
 // parseEarnings parses the earnings json returned from finnhub
@@ -146,10 +159,10 @@ func parseQuote(m map[string]interface{}, sec *security.Security) error {
 		return fmt.Errorf("unable to convert c to float64 %v", c)
 	}
 
-	now := time.Now()
+	// Quotes from the most recent session, or the one before it, are fresh.
 	quoteDate := time.Unix(int64(t.(float64)), 0)
-	sinceClose := date.TimeSinceClose(now)
-	stale := now.Sub(quoteDate) > (sinceClose + (24+6)*time.Hour + 30*time.Minute)
+	lastClose := date.LastClose(time.Now())
+	stale := date.TradingDaysBetween(quoteDate, lastClose) > 1
 
 	if c.(float64) == 0 || t.(float64) == 0 || stale {
 		msg := "From Finnhub"
@@ -179,11 +192,138 @@ func parseMetric(m map[string]interface{}, sec *security.Security) error {
 	// The pe key is optional; ignore it if not there
 	// When present, sometimes it is nil; remap that to zero
 	pe, err := web.MsiValued(metric, []string{"peBasicExclExtraTTM"}, 0.0)
-	if err != nil {
+	if err == nil {
+		sec.PE = pe.(float64)
+	}
+
+	// Same for the dividend yield
+	yield, err := web.MsiValued(metric, []string{"dividendYieldIndicatedAnnual"}, 0.0)
+	if err == nil {
+		sec.DividendYield = yield.(float64)
+	}
+
+	return nil
+}
+
+// industrySectors maps finnhub's industry classification to a broader sector
+var industrySectors = map[string]string{
+	"Aerospace & Defense":              "Industrials",
+	"Airlines":                         "Industrials",
+	"Building":                         "Industrials",
+	"Commercial Services & Supplies":   "Industrials",
+	"Construction":                     "Industrials",
+	"Electrical Equipment":             "Industrials",
+	"Industrial Conglomerates":         "Industrials",
+	"Logistics & Transportation":       "Industrials",
+	"Machinery":                        "Industrials",
+	"Marine":                           "Industrials",
+	"Professional Services":            "Industrials",
+	"Road & Rail":                      "Industrials",
+	"Trading Companies & Distributors": "Industrials",
+	"Transportation Infrastructure":    "Industrials",
+	"Auto Components":                  "Consumer Discretionary",
+	"Automobiles":                      "Consumer Discretionary",
+	"Diversified Consumer Services":    "Consumer Discretionary",
+	"Distributors":                     "Consumer Discretionary",
+	"Hotels, Restaurants & Leisure":    "Consumer Discretionary",
+	"Leisure Products":                 "Consumer Discretionary",
+	"Retail":                           "Consumer Discretionary",
+	"Textiles, Apparel & Luxury Goods": "Consumer Discretionary",
+	"Beverages":                        "Consumer Staples",
+	"Consumer products":                "Consumer Staples",
+	"Food Products":                    "Consumer Staples",
+	"Tobacco":                          "Consumer Staples",
+	"Banking":                          "Financials",
+	"Financial Services":               "Financials",
+	"Insurance":                        "Financials",
+	"Biotechnology":                    "Health Care",
+	"Health Care":                      "Health Care",
+	"Life Sciences Tools & Services":   "Health Care",
+	"Pharmaceuticals":                  "Health Care",
+	"Semiconductors":                   "Information Technology",
+	"Technology":                       "Information Technology",
+	"Communications":                   "Communication Services",
+	"Media":                            "Communication Services",
+	"Telecommunication":                "Communication Services",
+	"Chemicals":                        "Materials",
+	"Metals & Mining":                  "Materials",
+	"Packaging":                        "Materials",
+	"Energy":                           "Energy",
+	"Utilities":                        "Utilities",
+	"Real Estate":                      "Real Estate",
+}
+
+// parseProfile parses the company profile json returned from finnhub
+func parseProfile(m map[string]interface{}, sec *security.Security) error {
+	// {
+	//   "name": "Apple Inc",
+	//   "finnhubIndustry": "Technology",
+	//   "ticker": "AAPL",
+	//   ...
+	// }
+	// Funds and indexes have no profile; the response is empty.
+	name, err := web.MsiValued(m, []string{"name"}, "")
+	if err == nil {
+		sec.Name = name.(string)
+	}
+
+	industry, err := web.MsiValued(m, []string{"finnhubIndustry"}, "")
+	if err != nil || industry.(string) == "N/A" {
 		return nil
 	}
 
-	sec.PE = pe.(float64)
+	sec.Industry = industry.(string)
+	sec.Sector = industrySectors[sec.Industry]
+	if sec.Sector == "" {
+		sec.Sector = sec.Industry
+	}
+
+	return nil
+}
+
+// parseDividends parses the dividend json returned from finnhub and sets the
+// earliest upcoming ex-dividend date and amount
+func parseDividends(m map[string]interface{}, sec *security.Security, today string) error {
+	// {
+	//   "data": [
+	//     {
+	//       "symbol": "KO",
+	//       "date": "2024-09-13",   ex-dividend date
+	//       "amount": 0.485,
+	//       "payDate": "2024-10-01",
+	//       ...
+	//     }
+	//   ]
+	// }
+	data, err := web.MsiValue(m, []string{"data"})
+	if err != nil {
+		return fmt.Errorf("unable to parse dividend object %s", err)
+	}
+
+	entries, ok := data.([]interface{})
+	if !ok {
+		return fmt.Errorf("unable to convert dividend data to a list %v", data)
+	}
+
+	for _, entry := range entries {
+		d, err := web.MsiValue(entry, []string{"date"})
+		if err != nil {
+			return fmt.Errorf("unable to parse dividend date %s", err)
+		}
+		amount, err := web.MsiValued(entry, []string{"amount"}, 0.0)
+		if err != nil {
+			return fmt.Errorf("unable to parse dividend amount %s", err)
+		}
+
+		exDate := d.(string)
+		if exDate < today {
+			continue
+		}
+		if sec.ExDividendDate == "" || exDate < sec.ExDividendDate {
+			sec.ExDividendDate = exDate
+			sec.Dividend = amount.(float64)
+		}
+	}
 
 	return nil
 }
@@ -258,6 +398,43 @@ func getMetric(sec *security.Security) error {
 	return nil
 }
 
+// GetProfile looks up the company name, industry and sector of the security
+func GetProfile(sec *security.Security) error {
+	url := "https://finnhub.io/api/v1/stock/profile2?symbol=" + sec.Ticker
+
+	response, err := fetch(url)
+	if err != nil {
+		return fmt.Errorf("error fetching profile %s %s", sec.Ticker, err)
+	}
+
+	err = parseProfile(response, sec)
+	if err != nil {
+		return fmt.Errorf("error parsing profile %s", err)
+	}
+
+	return nil
+}
+
+// GetDividend looks up the next ex-dividend date (up to the latest
+// expiration) and amount for the security
+func GetDividend(sec *security.Security) error {
+	today := time.Now().Format("2006-01-02")
+
+	url := "https://finnhub.io/api/v1/stock/dividend?symbol=" + sec.Ticker + "&from=" + today + "&to=" + latestExpiration
+
+	response, err := fetch(url)
+	if err != nil {
+		return fmt.Errorf("error fetching dividends %s %s", sec.Ticker, err)
+	}
+
+	err = parseDividends(response, sec, today)
+	if err != nil {
+		return fmt.Errorf("error parsing dividends %s", err)
+	}
+
+	return nil
+}
+
 // GetStock looks up a single ticker symbol returns the sec
 func GetStock(sec *security.Security) error {
 	err := getQuote(sec)
@@ -0,0 +1,67 @@
+package finnhub
+
+import (
+	"testing"
+
+	"github.com/erikbryant/options/security"
+)
+
+func TestParseDividends(t *testing.T) {
+	entry := func(date string, amount float64) map[string]interface{} {
+		return map[string]interface{}{"symbol": "KO", "date": date, "amount": amount}
+	}
+
+	testCases := []struct {
+		m        map[string]interface{}
+		exDate   string
+		dividend float64
+		err      bool
+	}{
+		// The earliest upcoming ex-date wins, whatever the order
+		{map[string]interface{}{"data": []interface{}{entry("2024-12-13", 0.50), entry("2024-09-13", 0.485)}}, "2024-09-13", 0.485, false},
+		// Past dividends are ignored
+		{map[string]interface{}{"data": []interface{}{entry("2024-06-14", 0.485)}}, "", 0, false},
+		// Today's counts
+		{map[string]interface{}{"data": []interface{}{entry("2024-09-09", 0.485)}}, "2024-09-09", 0.485, false},
+		{map[string]interface{}{"data": []interface{}{}}, "", 0, false},
+		{map[string]interface{}{}, "", 0, true},
+		{map[string]interface{}{"data": "none"}, "", 0, true},
+		{map[string]interface{}{"data": []interface{}{map[string]interface{}{"amount": 0.485}}}, "", 0, true},
+	}
+
+	for _, testCase := range testCases {
+		sec := security.Security{}
+		err := parseDividends(testCase.m, &sec, "2024-09-09")
+		if (err != nil) != testCase.err {
+			t.Errorf("For %v expected error %v, got %v", testCase.m, testCase.err, err)
+		}
+		if sec.ExDividendDate != testCase.exDate || sec.Dividend != testCase.dividend {
+			t.Errorf("For %v expected %s/%f, got %s/%f", testCase.m, testCase.exDate, testCase.dividend, sec.ExDividendDate, sec.Dividend)
+		}
+	}
+}
+
+func TestParseProfile(t *testing.T) {
+	testCases := []struct {
+		m        map[string]interface{}
+		name     string
+		industry string
+		sector   string
+	}{
+		{map[string]interface{}{"name": "NVIDIA Corp", "finnhubIndustry": "Semiconductors"}, "NVIDIA Corp", "Semiconductors", "Information Technology"},
+		{map[string]interface{}{"name": "Odd Co", "finnhubIndustry": "Something New"}, "Odd Co", "Something New", "Something New"},
+		{map[string]interface{}{"name": "Fund", "finnhubIndustry": "N/A"}, "Fund", "", ""},
+		{map[string]interface{}{}, "", "", ""},
+	}
+
+	for _, testCase := range testCases {
+		sec := security.Security{}
+		err := parseProfile(testCase.m, &sec)
+		if err != nil {
+			t.Errorf("For %v unexpected error %s", testCase.m, err)
+		}
+		if sec.Name != testCase.name || sec.Industry != testCase.industry || sec.Sector != testCase.sector {
+			t.Errorf("For %v expected %s/%s/%s, got %s/%s/%s", testCase.m, testCase.name, testCase.industry, testCase.sector, sec.Name, sec.Industry, sec.Sector)
+		}
+	}
+}
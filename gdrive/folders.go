@@ -0,0 +1,100 @@
+package gdrive
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+const folderMimeType = "application/vnd.google-apps.folder"
+
+// Subfolder layouts
+const (
+	ByExpiration = "expiration" // one subfolder per expiration, e.g. 2024-06-07
+	ByWeek       = "week"       // one subfolder per ISO week of the expiration, e.g. 2024-W23
+)
+
+// FolderName returns the name of the subfolder sheets for the expiration go
+// in under the given layout ("" for none)
+func FolderName(layout, expiration string) (string, error) {
+	switch layout {
+	case "":
+		return "", nil
+	case ByExpiration:
+		return expiration, nil
+	case ByWeek:
+		t, err := time.Parse("2006-01-02", expiration)
+		if err != nil {
+			return "", fmt.Errorf("unable to parse expiration %s", err)
+		}
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week), nil
+	}
+
+	return "", fmt.Errorf("unknown folder layout '%s'", layout)
+}
+
+// Folder returns the ID of the named folder in the parent folder, creating it
+// if it does not exist yet
+func (g Google) Folder(name, parentID string) (string, error) {
+	srv, err := service()
+	if err != nil {
+		return "", err
+	}
+
+	q := fmt.Sprintf("name = '%s' and '%s' in parents and mimeType = '%s' and trashed = false",
+		strings.ReplaceAll(name, "'", "\\'"), parentID, folderMimeType)
+
+	list, err := srv.Files.List().Q(q).Fields("files(id, name)").Do()
+	if err != nil {
+		return "", fmt.Errorf("could not search for folder %s %s", name, err)
+	}
+	if len(list.Files) > 0 {
+		return list.Files[0].Id, nil
+	}
+
+	f := &drive.File{
+		MimeType: folderMimeType,
+		Name:     name,
+		Parents:  []string{parentID},
+	}
+
+	folder, err := srv.Files.Create(f).Fields("id").Do()
+	if err != nil {
+		return "", fmt.Errorf("could not create folder %s %s", name, err)
+	}
+
+	return folder.Id, nil
+}
+
+// Trash moves the file to the Drive trash
+func (g Google) Trash(fileID string) error {
+	srv, err := service()
+	if err != nil {
+		return err
+	}
+
+	_, err = srv.Files.Update(fileID, &drive.File{Trashed: true}).Do()
+	if err != nil {
+		return fmt.Errorf("could not trash file %s %s", fileID, err)
+	}
+
+	return nil
+}
+
+// Move moves the file from one folder to another
+func (g Google) Move(fileID, fromID, toID string) error {
+	srv, err := service()
+	if err != nil {
+		return err
+	}
+
+	_, err = srv.Files.Update(fileID, &drive.File{}).RemoveParents(fromID).AddParents(toID).Do()
+	if err != nil {
+		return fmt.Errorf("could not move file %s %s", fileID, err)
+	}
+
+	return nil
+}
@@ -19,10 +19,11 @@ package gdrive
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
-	"time"
+	"sync"
 
 	"github.com/pkg/browser"
 	"golang.org/x/oauth2"
@@ -32,6 +33,33 @@ import (
 	"google.golang.org/api/option"
 )
 
+var (
+	credentialsFile = "credentials.json"
+	tokenFile       = "token.json"
+	interactive     = isTerminal(os.Stdin)
+)
+
+// ErrLoginRequired is returned when we need someone to log in through the
+// browser, but are not running interactively
+var ErrLoginRequired = errors.New("interactive Google login required")
+
+// Init sets where the OAuth client (or service account) credentials and the
+// OAuth token are kept, and whether we may ask someone to log in
+func Init(credentials, token string, allowLogin bool) {
+	credentialsFile = credentials
+	tokenFile = token
+	interactive = allowLogin && isTerminal(os.Stdin)
+}
+
+// isTerminal returns whether f is a terminal someone could type into
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
 // Retrieves a token from a local file.
 func tokenFromFile(file string) (*oauth2.Token, error) {
 	f, err := os.Open(file)
@@ -50,8 +78,6 @@ func tokenFromFile(file string) (*oauth2.Token, error) {
 
 // Saves a token to a file path.
 func saveToken(path string, token *oauth2.Token) error {
-	fmt.Printf("Saving credential file to: %s\n", path)
-
 	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return fmt.Errorf("unable to cache oauth token: %v", err)
@@ -59,13 +85,46 @@ func saveToken(path string, token *oauth2.Token) error {
 
 	defer f.Close()
 
-	json.NewEncoder(f).Encode(token)
+	return json.NewEncoder(f).Encode(token)
+}
+
+// savingTokenSource saves each new token (e.g. after a refresh) to file, so
+// the next run starts from it
+type savingTokenSource struct {
+	src  oauth2.TokenSource
+	file string
+
+	mu   sync.Mutex
+	last string // the access token last saved
+}
+
+// Token returns a valid token, refreshing and saving it if need be
+func (s *savingTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := s.src.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	return nil
+	if tok.AccessToken != s.last {
+		err = saveToken(s.file, tok)
+		if err != nil {
+			return nil, err
+		}
+		s.last = tok.AccessToken
+	}
+
+	return tok, nil
 }
 
 // Request a token from the web, then returns the retrieved token.
 func getTokenFromWeb(config *oauth2.Config) (*oauth2.Token, error) {
+	if !interactive {
+		return nil, fmt.Errorf("%w; run once from a terminal to refresh %s", ErrLoginRequired, tokenFile)
+	}
+
 	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
 
 	fmt.Printf("Opening authorization link in your browser: \n%v\n\n", authURL)
@@ -86,72 +145,139 @@ func getTokenFromWeb(config *oauth2.Config) (*oauth2.Token, error) {
 	return tok, nil
 }
 
-// Retrieve a token, saves the token, then returns the generated client.
-func getClient(config *oauth2.Config) (*http.Client, error) {
-	// tokFile stores the user's access and refresh tokens. If it does not exist
-	// or has expired we will create it.
-	tokFile := "token.json"
+// tokenSource returns a source of valid tokens, starting from the saved
+// token. Expired tokens are refreshed (and saved) automatically. Someone only
+// needs to log in when there is no saved token or it can no longer be refreshed.
+func tokenSource(config *oauth2.Config, file string) (oauth2.TokenSource, error) {
+	ctx := context.Background()
 
-	tok, err := tokenFromFile(tokFile)
-	if err != nil || tok.Expiry.Before(time.Now()) {
-		tok, err = getTokenFromWeb(config)
-		if err != nil {
-			return nil, err
-		}
-		err = saveToken(tokFile, tok)
-		if err != nil {
-			return nil, err
+	tok, err := tokenFromFile(file)
+	if err == nil {
+		ts := &savingTokenSource{src: config.TokenSource(ctx, tok), file: file, last: tok.AccessToken}
+		_, err = ts.Token()
+		if err == nil {
+			return ts, nil
 		}
+		fmt.Printf("Unable to refresh Google token %s: %s\n", file, err)
+	}
+
+	tok, err = getTokenFromWeb(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ts := &savingTokenSource{src: config.TokenSource(ctx, tok), file: file}
+	_, err = ts.Token()
+	if err != nil {
+		return nil, err
 	}
 
-	return config.Client(context.Background(), tok), nil
+	return ts, nil
 }
 
-// service returns a Drive service that can be used to access Drive assets.
-func service() (*drive.Service, error) {
-	ctx := context.Background()
+// credentialType returns the type of the Google credentials file, e.g.
+// "service_account". OAuth client credentials have no type.
+func credentialType(b []byte) (string, error) {
+	var f struct {
+		Type string `json:"type"`
+	}
 
-	b, err := os.ReadFile("credentials.json")
+	err := json.Unmarshal(b, &f)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse credentials file %s %v", credentialsFile, err)
+	}
+
+	return f.Type, nil
+}
+
+// httpClient returns an authorized client for the Google APIs. The Sheets
+// API also accepts the Drive scope.
+func httpClient() (*http.Client, error) {
+	b, err := os.ReadFile(credentialsFile)
 	if err != nil {
 		return nil, fmt.Errorf("unable to read client secret file: %v", err)
 	}
 
-	// If you modify these scopes, delete the old token.json.
+	kind, err := credentialType(b)
+	if err != nil {
+		return nil, err
+	}
+
+	if kind == "service_account" {
+		// Service accounts need no login; share the folder with the account
+		config, err := google.JWTConfigFromJSON(b, drive.DriveScope)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse service account file to config: %v", err)
+		}
+		return config.Client(context.Background()), nil
+	}
+
 	config, err := google.ConfigFromJSON(b, drive.DriveScope)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse client secret file to config: %v", err)
 	}
 
-	client, err := getClient(config)
+	ts, err := tokenSource(config, tokenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return oauth2.NewClient(context.Background(), ts), nil
+}
+
+// service returns a Drive service that can be used to access Drive assets.
+func service() (*drive.Service, error) {
+	client, err := httpClient()
 	if err != nil {
 		return nil, err
 	}
 
-	srv, err := drive.NewService(ctx, option.WithHTTPClient(client))
+	srv, err := drive.NewService(context.Background(), option.WithHTTPClient(client))
 
 	return srv, err
 }
 
+// Drive is where we keep the sheets we generate
+type Drive interface {
+	// CreateSheet uploads a CSV file as a new sheet and returns its ID
+	CreateSheet(name, parentID string) (string, error)
+	// UpdateSheet replaces the contents of the sheet of the same name, keeping
+	// the values typed into the preserved columns, and returns its ID
+	UpdateSheet(name, parentID string, headerRows int, preserved []string) (string, error)
+	// Folder returns the ID of the named folder, creating it if need be
+	Folder(name, parentID string) (string, error)
+	// Trash moves the file to the trash
+	Trash(fileID string) error
+	// Move moves the file from one folder to another
+	Move(fileID, fromID, toID string) error
+}
+
+// Google is the real Google Drive
+type Google struct{}
+
 // CreateSheet uploads a CSV file as a Google Sheet in Google Drive.
-func CreateSheet(name string, parentID string) (*drive.File, error) {
+func (g Google) CreateSheet(name string, parentID string) (string, error) {
 	srv, err := service()
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	content, _ := os.Open(name)
+	content, err := os.Open(name)
+	if err != nil {
+		return "", fmt.Errorf("could not open %s %s", name, err)
+	}
 	defer content.Close()
 
 	f := &drive.File{
-		MimeType: "application/vnd.google-apps.spreadsheet",
+		MimeType: sheetMimeType,
 		Name:     name,
 		Parents:  []string{parentID},
 	}
 
 	file, err := srv.Files.Create(f).Media(content, googleapi.ContentType("text/csv")).Do()
 	if err != nil {
-		return nil, fmt.Errorf("could not create file %s", err.Error())
+		return "", fmt.Errorf("could not create file %s", err.Error())
 	}
 
-	return file, nil
+	return file.Id, nil
 }
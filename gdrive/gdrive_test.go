@@ -0,0 +1,94 @@
+package gdrive
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// sequenceSource returns its tokens in turn
+type sequenceSource struct {
+	tokens []*oauth2.Token
+}
+
+func (s *sequenceSource) Token() (*oauth2.Token, error) {
+	if len(s.tokens) == 0 {
+		return nil, errors.New("no more tokens")
+	}
+	tok := s.tokens[0]
+	if len(s.tokens) > 1 {
+		s.tokens = s.tokens[1:]
+	}
+	return tok, nil
+}
+
+func TestSavingTokenSource(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "token.json")
+	expiry := time.Now().Add(time.Hour).Round(time.Second)
+
+	src := &sequenceSource{tokens: []*oauth2.Token{
+		{AccessToken: "first", RefreshToken: "refresh", Expiry: expiry},
+		{AccessToken: "second", RefreshToken: "refresh", Expiry: expiry},
+	}}
+	ts := &savingTokenSource{src: src, file: file, last: "first"}
+
+	// An unchanged token is not saved
+	_, err := ts.Token()
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	_, err = tokenFromFile(file)
+	if err == nil {
+		t.Errorf("Expected no token to be saved")
+	}
+
+	// A refreshed token is
+	_, err = ts.Token()
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	tok, err := tokenFromFile(file)
+	if err != nil {
+		t.Fatalf("Expected the token to be saved %s", err)
+	}
+	if tok.AccessToken != "second" || tok.RefreshToken != "refresh" || !tok.Expiry.Equal(expiry) {
+		t.Errorf("Unexpected saved token %+v", tok)
+	}
+}
+
+func TestCredentialType(t *testing.T) {
+	testCases := []struct {
+		b        string
+		expected string
+	}{
+		{`{"installed": {"client_id": "x"}}`, ""},
+		{`{"type": "service_account", "client_email": "x@y"}`, "service_account"},
+	}
+
+	for _, testCase := range testCases {
+		answer, err := credentialType([]byte(testCase.b))
+		if err != nil {
+			t.Errorf("ERROR: For %s unexpected error %s", testCase.b, err)
+		}
+		if answer != testCase.expected {
+			t.Errorf("ERROR: For %s expected %s, got %s", testCase.b, testCase.expected, answer)
+		}
+	}
+
+	_, err := credentialType([]byte("not json"))
+	if err == nil {
+		t.Errorf("Expected an error")
+	}
+}
+
+func TestLoginRequired(t *testing.T) {
+	Init(filepath.Join(t.TempDir(), "credentials.json"), filepath.Join(t.TempDir(), "token.json"), false)
+
+	_, err := tokenSource(&oauth2.Config{}, tokenFile)
+	if !errors.Is(err, ErrLoginRequired) {
+		t.Errorf("Expected ErrLoginRequired, got %v", err)
+	}
+}
@@ -0,0 +1,249 @@
+package gdrive
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// LocalFile is a file or folder in a Local drive
+type LocalFile struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	ParentID string `json:"parentId"`
+	Folder   bool   `json:"folder,omitempty"`
+	Trashed  bool   `json:"trashed,omitempty"`
+}
+
+// Local is a stand-in for Google Drive that keeps everything in a local
+// directory: each sheet's contents in files/ID.csv and the folders, names
+// and IDs in drive.json. It is for tests and dry runs.
+type Local struct {
+	Dir string
+
+	mu sync.Mutex
+}
+
+// NewLocal returns a local drive kept in dir
+func NewLocal(dir string) (*Local, error) {
+	err := os.MkdirAll(filepath.Join(dir, "files"), 0755)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create local drive %s %s", dir, err)
+	}
+
+	return &Local{Dir: dir}, nil
+}
+
+// load returns the drive's files and folders
+func (l *Local) load() ([]LocalFile, error) {
+	files := []LocalFile{}
+
+	contents, err := os.ReadFile(filepath.Join(l.Dir, "drive.json"))
+	if os.IsNotExist(err) {
+		return files, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read local drive %s", err)
+	}
+
+	err = json.Unmarshal(contents, &files)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal local drive %s", err)
+	}
+
+	return files, nil
+}
+
+// save records the drive's files and folders
+func (l *Local) save(files []LocalFile) error {
+	s, err := json.MarshalIndent(files, "", " ")
+	if err != nil {
+		return fmt.Errorf("could not marshal local drive %s", err)
+	}
+
+	return os.WriteFile(filepath.Join(l.Dir, "drive.json"), s, 0644)
+}
+
+// Files returns the drive's files and folders
+func (l *Local) Files() ([]LocalFile, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.load()
+}
+
+// Path returns where the contents of the sheet are kept
+func (l *Local) Path(id string) string {
+	return filepath.Join(l.Dir, "files", id+".csv")
+}
+
+// find returns the index of the untrashed file (or folder) with the name in the parent
+func find(files []LocalFile, name, parentID string, folder bool) int {
+	for i, f := range files {
+		if f.Name == name && f.ParentID == parentID && f.Folder == folder && !f.Trashed {
+			return i
+		}
+	}
+	return -1
+}
+
+// lookup returns the index of the file with the ID
+func lookup(files []LocalFile, id string) (int, error) {
+	for i, f := range files {
+		if f.ID == id {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("no such file %s", id)
+}
+
+// add records a new file and returns its ID
+func (l *Local) add(files *[]LocalFile, name, parentID string, folder bool) string {
+	id := fmt.Sprintf("local-%d", len(*files)+1)
+	*files = append(*files, LocalFile{ID: id, Name: name, ParentID: parentID, Folder: folder})
+	return id
+}
+
+// writeSheet writes the rows as the contents of the sheet
+func (l *Local) writeSheet(id string, rows [][]string) error {
+	f, err := os.Create(l.Path(id))
+	if err != nil {
+		return fmt.Errorf("could not create file %s", err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	err = w.WriteAll(rows)
+	if err != nil {
+		return fmt.Errorf("could not write file %s", err)
+	}
+
+	return nil
+}
+
+// CreateSheet copies a CSV file into the drive as a new sheet
+func (l *Local) CreateSheet(name, parentID string) (string, error) {
+	rows, err := readCSV(name)
+	if err != nil {
+		return "", err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	files, err := l.load()
+	if err != nil {
+		return "", err
+	}
+
+	id := l.add(&files, name, parentID, false)
+
+	err = l.writeSheet(id, rows)
+	if err != nil {
+		return "", err
+	}
+
+	return id, l.save(files)
+}
+
+// UpdateSheet replaces the contents of the sheet of the same name in the
+// parent folder, keeping the values typed into the preserved columns, or
+// creates it if there is none
+func (l *Local) UpdateSheet(name, parentID string, headerRows int, preserved []string) (string, error) {
+	fresh, err := readCSV(name)
+	if err != nil {
+		return "", err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	files, err := l.load()
+	if err != nil {
+		return "", err
+	}
+
+	var id string
+	rows := fresh
+	if i := find(files, name, parentID, false); i >= 0 {
+		id = files[i].ID
+		old, err := readCSV(l.Path(id))
+		if err != nil {
+			return "", err
+		}
+		rows = Merge(old, fresh, headerRows, preserved)
+	} else {
+		id = l.add(&files, name, parentID, false)
+	}
+
+	err = l.writeSheet(id, rows)
+	if err != nil {
+		return "", err
+	}
+
+	return id, l.save(files)
+}
+
+// Folder returns the ID of the named folder in the parent folder, creating it
+// if it does not exist yet
+func (l *Local) Folder(name, parentID string) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	files, err := l.load()
+	if err != nil {
+		return "", err
+	}
+
+	if i := find(files, name, parentID, true); i >= 0 {
+		return files[i].ID, nil
+	}
+
+	id := l.add(&files, name, parentID, true)
+
+	return id, l.save(files)
+}
+
+// Trash marks the file as trashed
+func (l *Local) Trash(fileID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	files, err := l.load()
+	if err != nil {
+		return err
+	}
+
+	i, err := lookup(files, fileID)
+	if err != nil {
+		return fmt.Errorf("could not trash file %s", err)
+	}
+	files[i].Trashed = true
+
+	return l.save(files)
+}
+
+// Move moves the file from one folder to another
+func (l *Local) Move(fileID, fromID, toID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	files, err := l.load()
+	if err != nil {
+		return err
+	}
+
+	i, err := lookup(files, fileID)
+	if err != nil {
+		return fmt.Errorf("could not move file %s", err)
+	}
+	if files[i].ParentID != fromID {
+		return fmt.Errorf("could not move file %s: it is not in %s", fileID, fromID)
+	}
+	files[i].ParentID = toID
+
+	return l.save(files)
+}
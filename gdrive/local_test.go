@@ -0,0 +1,173 @@
+package gdrive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeFile writes contents to a file in dir and returns its path
+func writeFile(t *testing.T, dir, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	err := os.WriteFile(path, []byte(contents), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLocal(t *testing.T) {
+	var d Drive
+
+	l, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	d = l
+
+	week, err := d.Folder("2024-W23", "root")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	again, err := d.Folder("2024-W23", "root")
+	if err != nil || again != week {
+		t.Errorf("Expected the existing folder %s, got %s %v", week, again, err)
+	}
+
+	src := t.TempDir()
+	sheet := writeFile(t, src, "eb_2024-06-07_puts.csv", ",\n,\nTicker,Strike,Lots,Held,Notes\nKO,$  65.00,0,,\n")
+
+	id, err := d.CreateSheet(sheet, week)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	// Someone notes the trade
+	writeFile(t, filepath.Dir(l.Path(id)), id+".csv", ",\n,\nTicker,Strike,Lots,Held,Notes\nKO,$  65.00,0,2,rolled\n")
+
+	// The next run updates the sheet in place
+	writeFile(t, src, "eb_2024-06-07_puts.csv", ",\n,\nTicker,Strike,Lots,Held,Notes\nT,$  18.50,0,,\nKO,$  65.00,1,,\n")
+	updated, err := d.UpdateSheet(sheet, week, 3, Preserved(true))
+	if err != nil || updated != id {
+		t.Fatalf("Expected sheet %s to be updated, got %s %v", id, updated, err)
+	}
+
+	rows, err := readCSV(l.Path(id))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if len(rows) != 5 || rows[3][0] != "T" || rows[4][2] != "1" || rows[4][3] != "2" || rows[4][4] != "rolled" {
+		t.Errorf("Unexpected sheet contents %q", rows)
+	}
+
+	// An update with no existing sheet creates one
+	other, err := d.UpdateSheet(sheet, "root", 3, Preserved(true))
+	if err != nil || other == id {
+		t.Errorf("Expected a new sheet, got %s %v", other, err)
+	}
+
+	err = d.Move(id, "root", "archive")
+	if err == nil {
+		t.Errorf("Expected an error moving a file from the wrong folder")
+	}
+	err = d.Move(id, week, "archive")
+	if err != nil {
+		t.Errorf("Unexpected error %s", err)
+	}
+	err = d.Trash(other)
+	if err != nil {
+		t.Errorf("Unexpected error %s", err)
+	}
+	err = d.Trash("nonexistent")
+	if err == nil {
+		t.Errorf("Expected an error trashing a nonexistent file")
+	}
+
+	files, err := l.Files()
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	expected := []LocalFile{
+		{ID: "local-1", Name: "2024-W23", ParentID: "root", Folder: true},
+		{ID: "local-2", Name: sheet, ParentID: "archive"},
+		{ID: "local-3", Name: sheet, ParentID: "root", Trashed: true},
+	}
+	if len(files) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, files)
+	}
+	for i := range expected {
+		if files[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], files[i])
+		}
+	}
+}
+
+func TestRetain(t *testing.T) {
+	l, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	sheet := writeFile(t, t.TempDir(), "eb_2024-05-10_puts.csv", "Ticker\nKO\n")
+	now := time.Date(2024, 6, 8, 12, 0, 0, 0, time.UTC)
+
+	m := &Manifest{}
+	for i := 0; i < 3; i++ {
+		id, err := l.CreateSheet(sheet, "root")
+		if err != nil {
+			t.Fatalf("Unexpected error %s", err)
+		}
+		m.Add(Upload{Profile: "eb", Name: sheet, ID: id, FolderID: "root", Uploaded: now.AddDate(0, 0, -14*i)})
+	}
+
+	err = m.Retain(l, "eb", 1, "archive", now)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if m.Uploads[0].Removed != "" || m.Uploads[1].Removed != Archived || m.Uploads[2].FolderID != "archive" {
+		t.Errorf("Unexpected manifest after archiving %+v", m.Uploads)
+	}
+
+	m.Add(Upload{Profile: "eb", Name: sheet, ID: m.Uploads[0].ID, FolderID: "root", Uploaded: now.AddDate(0, 0, -28)})
+	err = m.Retain(l, "eb", 1, "", now)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if m.Uploads[0].Removed != Trashed {
+		t.Errorf("Expected the sheet to be trashed %+v", m.Uploads[0])
+	}
+
+	files, _ := l.Files()
+	if !files[0].Trashed || files[1].ParentID != "archive" || files[2].ParentID != "archive" {
+		t.Errorf("Unexpected drive after retention %+v", files)
+	}
+}
+
+func TestRetainFailure(t *testing.T) {
+	l, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	sheet := writeFile(t, t.TempDir(), "eb_2024-05-10_puts.csv", "Ticker\nKO\n")
+	id, err := l.CreateSheet(sheet, "root")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	now := time.Date(2024, 6, 8, 12, 0, 0, 0, time.UTC)
+	m := &Manifest{}
+	// Someone already deleted the first sheet by hand
+	m.Add(Upload{Profile: "eb", Name: "eb_2024-05-03_puts.csv", ID: "gone", FolderID: "root", Uploaded: now.AddDate(0, 0, -36)})
+	m.Add(Upload{Profile: "eb", Name: sheet, ID: id, FolderID: "root", Uploaded: now.AddDate(0, 0, -29)})
+
+	err = m.Retain(l, "eb", 4, "", now)
+	if err == nil {
+		t.Errorf("Expected an error for the missing sheet")
+	}
+	if m.Uploads[0].Removed != "" || m.Uploads[1].Removed != Trashed {
+		t.Errorf("Expected the second sheet to be trashed anyway, got %+v", m.Uploads)
+	}
+}
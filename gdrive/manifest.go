@@ -0,0 +1,119 @@
+package gdrive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// What retention did with an uploaded sheet
+const (
+	Trashed  = "trashed"
+	Archived = "archived"
+)
+
+// Upload records a sheet we uploaded
+type Upload struct {
+	Profile  string    `json:"profile"`
+	Name     string    `json:"name"`
+	ID       string    `json:"id"`
+	FolderID string    `json:"folderId"`
+	Uploaded time.Time `json:"uploaded"`
+	Removed  string    `json:"removed,omitempty"` // Trashed or Archived, once retention has run
+}
+
+// Manifest is the local record of the sheets we have uploaded
+type Manifest struct {
+	Uploads []Upload `json:"uploads"`
+}
+
+// LoadManifest reads the manifest from file. A missing file is an empty manifest.
+func LoadManifest(file string) (*Manifest, error) {
+	m := &Manifest{}
+
+	contents, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read upload manifest %s %s", file, err)
+	}
+
+	err = json.Unmarshal(contents, m)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal upload manifest %s %s", file, err)
+	}
+
+	return m, nil
+}
+
+// Save writes the manifest to file
+func (m *Manifest) Save(file string) error {
+	s, err := json.MarshalIndent(m, "", " ")
+	if err != nil {
+		return fmt.Errorf("could not marshal upload manifest %s", err)
+	}
+
+	return os.WriteFile(file, s, 0644)
+}
+
+// Add records an upload. Updating a sheet in place replaces its earlier record.
+func (m *Manifest) Add(u Upload) {
+	for i := range m.Uploads {
+		if m.Uploads[i].ID == u.ID {
+			m.Uploads[i] = u
+			return
+		}
+	}
+	m.Uploads = append(m.Uploads, u)
+}
+
+// Expired returns the indexes of the profile's sheets, not yet removed, that
+// were uploaded more than the given number of weeks before now
+func (m *Manifest) Expired(profile string, weeks int, now time.Time) []int {
+	cutoff := now.AddDate(0, 0, -7*weeks)
+
+	result := []int{}
+	for i, u := range m.Uploads {
+		if u.Profile == profile && u.Removed == "" && u.Uploaded.Before(cutoff) {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// Retain trashes the profile's sheets uploaded more than the given number of
+// weeks ago or, if archiveID is set, moves them to that folder instead
+func (m *Manifest) Retain(d Drive, profile string, weeks int, archiveID string, now time.Time) error {
+	if weeks <= 0 {
+		return nil
+	}
+
+	// Carry on past a failure, so one missing sheet does not hold up the rest
+	errs := []error{}
+	for _, i := range m.Expired(profile, weeks, now) {
+		u := &m.Uploads[i]
+
+		if archiveID != "" {
+			err := d.Move(u.ID, u.FolderID, archiveID)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			u.FolderID = archiveID
+			u.Removed = Archived
+			continue
+		}
+
+		err := d.Trash(u.ID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		u.Removed = Trashed
+	}
+
+	return errors.Join(errs...)
+}
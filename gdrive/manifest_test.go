@@ -0,0 +1,79 @@
+package gdrive
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFolderName(t *testing.T) {
+	testCases := []struct {
+		layout     string
+		expiration string
+		expected   string
+	}{
+		{"", "2024-06-07", ""},
+		{ByExpiration, "2024-06-07", "2024-06-07"},
+		{ByWeek, "2024-06-07", "2024-W23"},
+		{ByWeek, "2021-01-01", "2020-W53"},
+	}
+
+	for _, testCase := range testCases {
+		answer, err := FolderName(testCase.layout, testCase.expiration)
+		if err != nil {
+			t.Errorf("ERROR: For %s %s unexpected error %s", testCase.layout, testCase.expiration, err)
+		}
+		if answer != testCase.expected {
+			t.Errorf("ERROR: For %s %s expected %s, got %s", testCase.layout, testCase.expiration, testCase.expected, answer)
+		}
+	}
+
+	_, err := FolderName("month", "2024-06-07")
+	if err == nil {
+		t.Errorf("Expected an error for an unknown layout")
+	}
+	_, err = FolderName(ByWeek, "June 7")
+	if err == nil {
+		t.Errorf("Expected an error for a bad expiration")
+	}
+}
+
+func TestManifest(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "manifest.json")
+
+	m, err := LoadManifest(file)
+	if err != nil || len(m.Uploads) != 0 {
+		t.Fatalf("Expected an empty manifest, got %v %v", m, err)
+	}
+
+	now := time.Date(2024, 6, 8, 12, 0, 0, 0, time.UTC)
+	m.Add(Upload{Profile: "eb", Name: "eb_2024-05-10_puts.csv", ID: "1", Uploaded: now.AddDate(0, 0, -29)})
+	m.Add(Upload{Profile: "cc", Name: "cc_2024-05-10_puts.csv", ID: "2", Uploaded: now.AddDate(0, 0, -29)})
+	m.Add(Upload{Profile: "eb", Name: "eb_2024-05-31_puts.csv", ID: "3", Uploaded: now.AddDate(0, 0, -8)})
+	m.Add(Upload{Profile: "eb", Name: "eb_2024-05-03_puts.csv", ID: "4", Uploaded: now.AddDate(0, 0, -36), Removed: Trashed})
+
+	// Updating a sheet in place replaces its record
+	m.Add(Upload{Profile: "eb", Name: "eb_2024-05-31_puts.csv", ID: "3", Uploaded: now})
+	if len(m.Uploads) != 4 || !m.Uploads[2].Uploaded.Equal(now) {
+		t.Errorf("Expected the record to be replaced, got %v", m.Uploads)
+	}
+
+	err = m.Save(file)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	m, err = LoadManifest(file)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	expired := m.Expired("eb", 4, now)
+	if !slices.Equal(expired, []int{0}) {
+		t.Errorf("Expected [0], got %v", expired)
+	}
+	expired = m.Expired("eb", 5, now)
+	if len(expired) != 0 {
+		t.Errorf("Expected none, got %v", expired)
+	}
+}
@@ -0,0 +1,332 @@
+package gdrive
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+const sheetMimeType = "application/vnd.google-apps.spreadsheet"
+
+var (
+	// KeyColumns identify a row across runs (those present in both sheets are used)
+	KeyColumns = []string{"Ticker", "Expiration", "Strike"}
+	// PreservedColumns are typed in by hand, so an update keeps their values
+	PreservedColumns = []string{"Held", "Notes"}
+	// UnsizedColumns are also typed in by hand, unless the profile sizes its
+	// trades; then Lots is the sizing's suggestion for this run
+	UnsizedColumns = []string{"Lots"}
+)
+
+// Preserved returns the columns an update keeps for a profile that does (or
+// does not) size its trades
+func Preserved(sized bool) []string {
+	if sized {
+		return PreservedColumns
+	}
+	return append(append([]string{}, PreservedColumns...), UnsizedColumns...)
+}
+
+// sheetsService returns a Sheets service that can be used to edit sheet contents.
+func sheetsService() (*sheets.Service, error) {
+	client, err := httpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return sheets.NewService(context.Background(), option.WithHTTPClient(client))
+}
+
+// columnIndex returns the index of each column in the header row
+func columnIndex(header []string) map[string]int {
+	index := map[string]int{}
+	for i, name := range header {
+		index[strings.TrimSpace(name)] = i
+	}
+	return index
+}
+
+// cell returns the trimmed value of a cell, or "" if the row is too short
+func cell(row []string, i int) string {
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// normalize returns a cell value in a form that compares equal however the
+// sheet chose to display it (e.g. "65", " 65.00" and "$  65.00")
+func normalize(value string) string {
+	value = strings.TrimSpace(value)
+
+	number := strings.TrimSuffix(strings.TrimPrefix(value, "$"), "%")
+	number = strings.ReplaceAll(strings.TrimSpace(number), ",", "")
+	if f, err := strconv.ParseFloat(number, 64); err == nil {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	return value
+}
+
+// Merge returns the fresh rows with the values of the preserved columns
+// carried over from the matching rows of the old ones. The last of the
+// headerRows holds the column names; rows match on the key columns both
+// sheets have. Only non-blank old values are carried over.
+func Merge(old, fresh [][]string, headerRows int, preserved []string) [][]string {
+	if len(old) < headerRows || len(fresh) < headerRows {
+		return fresh
+	}
+
+	oldCols := columnIndex(old[headerRows-1])
+	freshCols := columnIndex(fresh[headerRows-1])
+
+	keys := []string{}
+	for _, key := range KeyColumns {
+		_, inOld := oldCols[key]
+		_, inFresh := freshCols[key]
+		if inOld && inFresh {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return fresh
+	}
+
+	rowKey := func(row []string, cols map[string]int) string {
+		parts := []string{}
+		for _, key := range keys {
+			parts = append(parts, normalize(cell(row, cols[key])))
+		}
+		return strings.Join(parts, "|")
+	}
+
+	oldRows := map[string][]string{}
+	for _, row := range old[headerRows:] {
+		oldRows[rowKey(row, oldCols)] = row
+	}
+
+	result := [][]string{}
+	for i, row := range fresh {
+		row = append([]string{}, row...)
+		if i >= headerRows {
+			if oldRow, ok := oldRows[rowKey(row, freshCols)]; ok {
+				for _, col := range preserved {
+					o, inOld := oldCols[col]
+					f, inFresh := freshCols[col]
+					if !inOld || !inFresh || f >= len(row) {
+						continue
+					}
+					if value := cell(oldRow, o); value != "" {
+						row[f] = value
+					}
+				}
+			}
+		}
+		result = append(result, row)
+	}
+
+	return result
+}
+
+// readCSV returns the rows of a CSV file
+func readCSV(name string) ([][]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s %s", name, err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s %s", name, err)
+	}
+
+	return rows, nil
+}
+
+// findSheet returns the Google Sheet with the given name in the parent folder, if any
+func findSheet(srv *drive.Service, name, parentID string) (*drive.File, error) {
+	q := fmt.Sprintf("name = '%s' and '%s' in parents and mimeType = '%s' and trashed = false",
+		strings.ReplaceAll(name, "'", "\\'"), parentID, sheetMimeType)
+
+	list, err := srv.Files.List().Q(q).Fields("files(id, name)").Do()
+	if err != nil {
+		return nil, fmt.Errorf("could not search for %s %s", name, err)
+	}
+
+	if len(list.Files) == 0 {
+		return nil, nil
+	}
+
+	return list.Files[0], nil
+}
+
+// formatRequests returns the requests that freeze the header rows and
+// replace the sheet's conditional formatting with ours: earnings before
+// expiration in red and a white-to-green scale on the yield ratios
+func formatRequests(sheet *sheets.Sheet, header []string, headerRows int) []*sheets.Request {
+	id := sheet.Properties.SheetId
+
+	requests := []*sheets.Request{
+		{
+			UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+				Properties: &sheets.SheetProperties{
+					SheetId:        id,
+					GridProperties: &sheets.GridProperties{FrozenRowCount: int64(headerRows)},
+				},
+				Fields: "gridProperties.frozenRowCount",
+			},
+		},
+	}
+
+	for range sheet.ConditionalFormats {
+		requests = append(requests, &sheets.Request{
+			DeleteConditionalFormatRule: &sheets.DeleteConditionalFormatRuleRequest{SheetId: id, Index: 0, ForceSendFields: []string{"Index"}},
+		})
+	}
+
+	column := func(i int) []*sheets.GridRange {
+		return []*sheets.GridRange{{
+			SheetId:          id,
+			StartRowIndex:    int64(headerRows),
+			StartColumnIndex: int64(i),
+			EndColumnIndex:   int64(i + 1),
+		}}
+	}
+
+	for i, name := range header {
+		var rule *sheets.ConditionalFormatRule
+
+		switch strings.TrimSpace(name) {
+		case "Earnings":
+			rule = &sheets.ConditionalFormatRule{
+				Ranges: column(i),
+				BooleanRule: &sheets.BooleanRule{
+					Condition: &sheets.BooleanCondition{
+						Type:   "TEXT_EQ",
+						Values: []*sheets.ConditionValue{{UserEnteredValue: "E"}},
+					},
+					Format: &sheets.CellFormat{BackgroundColor: &sheets.Color{Red: 0.96, Green: 0.8, Blue: 0.8}},
+				},
+			}
+		case "B/S ratio", "B/P ratio", "Net Annualized":
+			rule = &sheets.ConditionalFormatRule{
+				Ranges: column(i),
+				GradientRule: &sheets.GradientRule{
+					Minpoint: &sheets.InterpolationPoint{Type: "MIN", Color: &sheets.Color{Red: 1, Green: 1, Blue: 1}},
+					Maxpoint: &sheets.InterpolationPoint{Type: "MAX", Color: &sheets.Color{Red: 0.72, Green: 0.88, Blue: 0.8}},
+				},
+			}
+		}
+
+		if rule != nil {
+			requests = append(requests, &sheets.Request{
+				AddConditionalFormatRule: &sheets.AddConditionalFormatRuleRequest{Rule: rule, Index: 0, ForceSendFields: []string{"Index"}},
+			})
+		}
+	}
+
+	return requests
+}
+
+// UpdateSheet replaces the contents of the Google Sheet named after the CSV
+// file in the parent folder with the CSV's, keeping the values typed into the
+// preserved columns, and formats it. If there is no such sheet it is created.
+func (g Google) UpdateSheet(name, parentID string, headerRows int, preserved []string) (string, error) {
+	fresh, err := readCSV(name)
+	if err != nil {
+		return "", err
+	}
+
+	srv, err := service()
+	if err != nil {
+		return "", err
+	}
+
+	existing, err := findSheet(srv, name, parentID)
+	if err != nil {
+		return "", err
+	}
+
+	var id string
+	if existing == nil {
+		id, err = g.CreateSheet(name, parentID)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		id = existing.Id
+	}
+
+	ss, err := sheetsService()
+	if err != nil {
+		return "", err
+	}
+
+	if existing != nil {
+		current, err := ss.Spreadsheets.Values.Get(id, "A:ZZ").ValueRenderOption("FORMULA").DateTimeRenderOption("FORMATTED_STRING").Do()
+		if err != nil {
+			return "", fmt.Errorf("could not read sheet %s %s", name, err)
+		}
+
+		old := [][]string{}
+		for _, r := range current.Values {
+			row := []string{}
+			for _, v := range r {
+				row = append(row, fmt.Sprint(v))
+			}
+			old = append(old, row)
+		}
+
+		rows := Merge(old, fresh, headerRows, preserved)
+
+		values := [][]interface{}{}
+		for _, r := range rows {
+			row := []interface{}{}
+			for _, v := range r {
+				row = append(row, v)
+			}
+			values = append(values, row)
+		}
+
+		_, err = ss.Spreadsheets.Values.Clear(id, "A:ZZ", &sheets.ClearValuesRequest{}).Do()
+		if err != nil {
+			return "", fmt.Errorf("could not clear sheet %s %s", name, err)
+		}
+
+		_, err = ss.Spreadsheets.Values.Update(id, "A1", &sheets.ValueRange{Values: values}).ValueInputOption("USER_ENTERED").Do()
+		if err != nil {
+			return "", fmt.Errorf("could not update sheet %s %s", name, err)
+		}
+	}
+
+	if len(fresh) < headerRows {
+		return id, nil
+	}
+
+	spreadsheet, err := ss.Spreadsheets.Get(id).Fields("sheets(properties.sheetId,conditionalFormats)").Do()
+	if err != nil {
+		return "", fmt.Errorf("could not read sheet %s %s", name, err)
+	}
+	if len(spreadsheet.Sheets) == 0 {
+		return id, nil
+	}
+
+	requests := formatRequests(spreadsheet.Sheets[0], fresh[headerRows-1], headerRows)
+	_, err = ss.Spreadsheets.BatchUpdate(id, &sheets.BatchUpdateSpreadsheetRequest{Requests: requests}).Do()
+	if err != nil {
+		return "", fmt.Errorf("could not format sheet %s %s", name, err)
+	}
+
+	return id, nil
+}
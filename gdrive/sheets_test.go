@@ -0,0 +1,126 @@
+package gdrive
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestMerge(t *testing.T) {
+	// As read back from Sheets
+	old := [][]string{
+		{"Fill: bid"},
+		{"", "", "=sum(C4:C9999)"},
+		{"  Ticker", "  Strike", "    Lots", "    Held", "   Notes"},
+		{"KO", "65", "3", "2", "rolled"},
+		{"T", "18.5", "1", "", "watch"},
+		{"PFE", "30", "2", "1", ""},
+	}
+
+	// As the sheet is written
+	fresh := [][]string{
+		{"Fill: mid"},
+		{"", "", "=sum(C4:C9999)", "", ""},
+		{"  Ticker", "  Strike", "    Lots", "    Held", "   Notes", "     Bid"},
+		{"       T", "$  18.50", "       4", "        ", "        ", "0.21"},
+		{"      KO", "$  65.00", "       0", "        ", "        ", "0.55"},
+		{"      KO", "$  67.50", "       1", "        ", "        ", "0.30"},
+	}
+
+	// A sized profile's lots are this run's suggestion
+	expected := [][]string{
+		{"Fill: mid"},
+		{"", "", "=sum(C4:C9999)", "", ""},
+		{"  Ticker", "  Strike", "    Lots", "    Held", "   Notes", "     Bid"},
+		{"       T", "$  18.50", "       4", "        ", "watch", "0.21"},
+		{"      KO", "$  65.00", "       0", "2", "rolled", "0.55"},
+		{"      KO", "$  67.50", "       1", "        ", "        ", "0.30"},
+	}
+
+	answer := Merge(old, fresh, 3, Preserved(true))
+	if !reflect.DeepEqual(answer, expected) {
+		t.Errorf("Expected %q, got %q", expected, answer)
+	}
+
+	// The fresh rows are not modified
+	if fresh[4][3] != "        " {
+		t.Errorf("Merge modified its input %q", fresh[4])
+	}
+
+	// Without sizing, the lots were typed in by hand and are kept too
+	expected[3][2], expected[4][2] = "1", "3"
+	answer = Merge(old, fresh, 3, Preserved(false))
+	if !reflect.DeepEqual(answer, expected) {
+		t.Errorf("Expected %q, got %q", expected, answer)
+	}
+	if len(PreservedColumns) != 2 {
+		t.Errorf("Preserved modified PreservedColumns %q", PreservedColumns)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected string
+	}{
+		{"65", "65"},
+		{"   65.00", "65"},
+		{"$  65.00", "65"},
+		{"$1,065.50", "1065.5"},
+		{"  12.3%", "12.3"},
+		{"      KO", "KO"},
+		{"2024-06-14", "2024-06-14"},
+	}
+
+	for _, testCase := range testCases {
+		answer := normalize(testCase.value)
+		if answer != testCase.expected {
+			t.Errorf("ERROR: For %q expected %q, got %q", testCase.value, testCase.expected, answer)
+		}
+	}
+}
+
+func TestMergeNoKeys(t *testing.T) {
+	old := [][]string{{""}, {""}, {"Price", "Notes"}, {"65", "rolled"}}
+	fresh := [][]string{{""}, {""}, {"Price", "Notes"}, {"65", ""}}
+
+	answer := Merge(old, fresh, 3, PreservedColumns)
+	if !reflect.DeepEqual(answer, fresh) {
+		t.Errorf("Expected %q, got %q", fresh, answer)
+	}
+
+	answer = Merge(nil, fresh, 3, PreservedColumns)
+	if !reflect.DeepEqual(answer, fresh) {
+		t.Errorf("Expected %q, got %q", fresh, answer)
+	}
+}
+
+func TestFormatRequests(t *testing.T) {
+	sheet := &sheets.Sheet{
+		Properties:         &sheets.SheetProperties{SheetId: 7},
+		ConditionalFormats: []*sheets.ConditionalFormatRule{{}, {}},
+	}
+	header := []string{"  Ticker", "B/S ratio", "Earnings"}
+
+	requests := formatRequests(sheet, header, 3)
+
+	// Freeze, delete the two old rules, add the two new ones
+	if len(requests) != 5 {
+		t.Fatalf("Expected 5 requests, got %d", len(requests))
+	}
+	if requests[0].UpdateSheetProperties.Properties.GridProperties.FrozenRowCount != 3 {
+		t.Errorf("Expected the header rows to be frozen")
+	}
+	if requests[1].DeleteConditionalFormatRule == nil || requests[2].DeleteConditionalFormatRule == nil {
+		t.Errorf("Expected the old rules to be deleted")
+	}
+	ratio := requests[3].AddConditionalFormatRule.Rule
+	if ratio.GradientRule == nil || ratio.Ranges[0].StartColumnIndex != 1 || ratio.Ranges[0].StartRowIndex != 3 {
+		t.Errorf("Unexpected ratio rule %+v", ratio)
+	}
+	earnings := requests[4].AddConditionalFormatRule.Rule
+	if earnings.BooleanRule == nil || earnings.Ranges[0].StartColumnIndex != 2 {
+		t.Errorf("Unexpected earnings rule %+v", earnings)
+	}
+}
@@ -0,0 +1,164 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/erikbryant/options/date"
+	"github.com/erikbryant/options/lookback"
+	"github.com/erikbryant/options/marketData"
+	"github.com/erikbryant/options/security"
+)
+
+// Unlike the web request cache, history is kept from run to run. Only the
+// days we do not already have are requested.
+const historyDir = "./price-history/"
+
+// record is the history we keep for a single ticker
+type record struct {
+	Candles []security.DayRange `json:"candles"`
+	IV      map[string]float64  `json:"iv"` // at-the-money IV, by day
+}
+
+// load returns the stored history for a ticker (empty if there is none)
+func load(ticker string) record {
+	rec := record{IV: map[string]float64{}}
+
+	contents, err := os.ReadFile(path.Join(historyDir, ticker+".json"))
+	if err != nil {
+		return rec
+	}
+
+	err = json.Unmarshal(contents, &rec)
+	if err != nil {
+		fmt.Printf("Ignoring corrupt history for %s %s\n", ticker, err)
+		return record{IV: map[string]float64{}}
+	}
+	if rec.IV == nil {
+		rec.IV = map[string]float64{}
+	}
+
+	return rec
+}
+
+// save stores the history for a ticker
+func save(ticker string, rec record) error {
+	err := os.MkdirAll(historyDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	s, err := json.MarshalIndent(rec, "", " ")
+	if err != nil {
+		return fmt.Errorf("could not marshal history for %s %s", ticker, err)
+	}
+
+	return os.WriteFile(path.Join(historyDir, ticker+".json"), s, 0644)
+}
+
+// merge combines two sets of candles, preferring newer over older for any
+// day in both, and returns them in date order
+func merge(older, newer []security.DayRange) []security.DayRange {
+	byDate := map[string]security.DayRange{}
+	for _, candle := range older {
+		byDate[candle.Date] = candle
+	}
+	for _, candle := range newer {
+		byDate[candle.Date] = candle
+	}
+
+	merged := []security.DayRange{}
+	for _, candle := range byDate {
+		merged = append(merged, candle)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Date < merged[j].Date })
+
+	return merged
+}
+
+// within returns the candles from startDate to endDate, inclusive
+func within(candles []security.DayRange, startDate, endDate string) []security.DayRange {
+	result := []security.DayRange{}
+	for _, candle := range candles {
+		if candle.Date >= startDate && candle.Date <= endDate {
+			result = append(result, candle)
+		}
+	}
+	return result
+}
+
+// Candles returns the daily candles for a ticker from startDate to endDate,
+// inclusive, fetching only the days not already in the stored history.
+func Candles(ticker, startDate, endDate string) ([]security.DayRange, error) {
+	rec := load(ticker)
+
+	from := startDate
+	n := len(rec.Candles)
+	if n > 0 && rec.Candles[0].Date <= startDate {
+		last, err := date.ParseDay(rec.Candles[n-1].Date)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse history date %s %s", rec.Candles[n-1].Date, err)
+		}
+		from = date.Format(date.NextTradingDay(last))
+	}
+
+	if from <= endDate {
+		candles, err := marketData.Candles(ticker, from, endDate)
+		if err != nil {
+			return nil, err
+		}
+		rec.Candles = merge(rec.Candles, candles)
+		err = save(ticker, rec)
+		if err != nil {
+			fmt.Printf("Unable to save history for %s %s\n", ticker, err)
+		}
+	}
+
+	return within(rec.Candles, startDate, endDate), nil
+}
+
+// Precache fetches the candle history of each ticker, so a later run only
+// needs to fetch the days since
+func Precache(tickers []string, now time.Time) {
+	end := lookback.HistoryEnd(now)
+	startDate := lookback.HistoryStart(end)
+	endDate := date.Format(end)
+	fmt.Printf("Using candles from %s to %s for trailing price %%change\n\n", startDate, endDate)
+	for _, symbol := range tickers {
+		fmt.Printf("\r%s    ", symbol)
+		_, err := Candles(symbol, startDate, endDate)
+		if err != nil {
+			fmt.Printf("error getting candles: %s %s\n", symbol, err)
+		}
+	}
+	fmt.Println()
+}
+
+// RecordIV stores the at-the-money implied volatility of a ticker for a day
+func RecordIV(ticker, day string, iv float64) error {
+	rec := load(ticker)
+	rec.IV[day] = iv
+	return save(ticker, rec)
+}
+
+// IVs returns the recorded implied volatilities of a ticker over the year
+// ending on the given day
+func IVs(ticker string, end time.Time) []float64 {
+	rec := load(ticker)
+
+	first := date.Format(end.AddDate(-1, 0, 0))
+	last := date.Format(end)
+
+	ivs := []float64{}
+	for day, iv := range rec.IV {
+		if day > first && day <= last {
+			ivs = append(ivs, iv)
+		}
+	}
+
+	return ivs
+}
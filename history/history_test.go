@@ -0,0 +1,48 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/erikbryant/options/security"
+)
+
+func TestMerge(t *testing.T) {
+	older := []security.DayRange{
+		{Date: "2024-09-09", Close: 1},
+		{Date: "2024-09-10", Close: 2},
+	}
+	newer := []security.DayRange{
+		{Date: "2024-09-11", Close: 3},
+		{Date: "2024-09-10", Close: 20},
+	}
+
+	merged := merge(older, newer)
+
+	expected := []security.DayRange{
+		{Date: "2024-09-09", Close: 1},
+		{Date: "2024-09-10", Close: 20},
+		{Date: "2024-09-11", Close: 3},
+	}
+
+	if len(merged) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, merged)
+	}
+	for i := range expected {
+		if merged[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, merged)
+		}
+	}
+}
+
+func TestWithin(t *testing.T) {
+	candles := []security.DayRange{
+		{Date: "2024-09-09"},
+		{Date: "2024-09-10"},
+		{Date: "2024-09-11"},
+	}
+
+	answer := within(candles, "2024-09-10", "2024-09-11")
+	if len(answer) != 2 || answer[0].Date != "2024-09-10" {
+		t.Errorf("Expected 2024-09-10 to 2024-09-11, got %v", answer)
+	}
+}
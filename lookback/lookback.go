@@ -0,0 +1,128 @@
+package lookback
+
+import (
+	"time"
+
+	"github.com/erikbryant/options/date"
+	"github.com/erikbryant/options/security"
+)
+
+// Window is a trailing period over which we measure the change in share price
+type Window struct {
+	Name string
+	// start returns the trading day the window opens on, given the day it closes on
+	start func(end time.Time) time.Time
+}
+
+// Windows are the trailing periods we know how to compute. Each one is
+// available as a "priceChange<Name>" column.
+var Windows = []Window{
+	{
+		Name:  "1d",
+		start: date.PreviousTradingDay,
+	},
+	{
+		Name: "1w",
+		start: func(end time.Time) time.Time {
+			return date.OnOrBefore(end.AddDate(0, 0, -7))
+		},
+	},
+	{
+		Name: "1m",
+		start: func(end time.Time) time.Time {
+			return date.OnOrBefore(end.AddDate(0, -1, 0))
+		},
+	},
+	{
+		// Close on the last trading day of the previous year
+		Name: "YTD",
+		start: func(end time.Time) time.Time {
+			return date.OnOrBefore(time.Date(end.Year()-1, time.December, 31, 12, 0, 0, 0, end.Location()))
+		},
+	},
+}
+
+// The 52-week range windows measure the distance from the high/low rather
+// than the change over the period.
+const (
+	High52w = "52wHigh"
+	Low52w  = "52wLow"
+)
+
+// HistoryStart returns the first day of candle history needed to compute every window.
+func HistoryStart(end time.Time) string {
+	return date.Format(date.OnOrBefore(end.AddDate(-1, 0, -7)))
+}
+
+// HistoryEnd returns the last trading day that has closed as of now.
+func HistoryEnd(now time.Time) time.Time {
+	return date.OnOrBefore(date.LastClose(now))
+}
+
+// closeOn returns the close on the given day, or the latest close before it
+// if there is a gap in the candles.
+func closeOn(candles []security.DayRange, day string) (float64, bool) {
+	found := false
+	c := 0.0
+	for _, candle := range candles {
+		if candle.Date > day {
+			break
+		}
+		c = candle.Close
+		found = true
+	}
+	return c, found
+}
+
+// pct returns the percent change from start to end
+func pct(start, end float64) float64 {
+	return 100.0 * (end - start) / start
+}
+
+// Changes returns the percent price change for each window, keyed by window
+// name, from date-ordered candles that close on the given end day. Windows
+// the candles do not cover are omitted.
+func Changes(candles []security.DayRange, end time.Time) map[string]float64 {
+	changes := map[string]float64{}
+
+	last, ok := closeOn(candles, date.Format(end))
+	if !ok || last == 0 {
+		return changes
+	}
+
+	for _, w := range Windows {
+		start := date.Format(w.start(end))
+		if len(candles) == 0 || candles[0].Date > start {
+			continue
+		}
+		c, ok := closeOn(candles, start)
+		if !ok || c == 0 {
+			continue
+		}
+		changes[w.Name] = pct(c, last)
+	}
+
+	// 52-week range
+	yearAgo := date.Format(end.AddDate(-1, 0, 0))
+	high := 0.0
+	low := 0.0
+	for _, candle := range candles {
+		if candle.Date <= yearAgo || candle.Date > date.Format(end) {
+			continue
+		}
+		if candle.High > high {
+			high = candle.High
+		}
+		if low == 0 || candle.Low < low {
+			low = candle.Low
+		}
+	}
+	if high > 0 {
+		changes[High52w] = pct(high, last)
+	}
+	if low > 0 {
+		changes[Low52w] = pct(low, last)
+	}
+
+	return changes
+}
@@ -0,0 +1,82 @@
+package lookback
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/erikbryant/options/date"
+	"github.com/erikbryant/options/security"
+)
+
+// candles returns one candle per trading day from start to end, with the
+// close on each day produced by price
+func candles(start, end time.Time, price func(day time.Time) float64) []security.DayRange {
+	var result []security.DayRange
+	for day := date.OnOrAfter(start); !day.After(end); day = date.NextTradingDay(day) {
+		p := price(day)
+		result = append(result, security.DayRange{
+			Date:  date.Format(day),
+			Open:  p,
+			High:  p + 1,
+			Low:   p - 1,
+			Close: p,
+		})
+	}
+	return result
+}
+
+func TestChanges(t *testing.T) {
+	end, _ := date.ParseDay("2024-09-13")
+	start, _ := date.ParseDay(HistoryStart(end))
+
+	// The price is the day of the month, plus 100 per month since January 2023
+	history := candles(start, end, func(day time.Time) float64 {
+		return float64((day.Year()-2023)*1200 + int(day.Month())*100 + day.Day())
+	})
+
+	changes := Changes(history, end)
+
+	// 2024-09-13 closes at 2113
+	testCases := []struct {
+		window   string
+		expected float64
+	}{
+		{"1d", 100.0 * (2113 - 2112) / 2112},
+		{"1w", 100.0 * (2113 - 2106) / 2106},
+		// 2024-08-13
+		{"1m", 100.0 * (2113 - 2013) / 2013},
+		// 2023-12-29
+		{"YTD", 100.0 * (2113 - 1229) / 1229},
+		{High52w, 100.0 * (2113 - 2114) / 2114},
+		// 2023-09-14 is the first day inside the 52 week window
+		{Low52w, 100.0 * (2113 - 913) / 913},
+	}
+
+	for _, testCase := range testCases {
+		answer, ok := changes[testCase.window]
+		if !ok {
+			t.Errorf("For %s expected %f, got nothing", testCase.window, testCase.expected)
+			continue
+		}
+		if math.Abs(answer-testCase.expected) > 0.0001 {
+			t.Errorf("For %s expected %f, got %f", testCase.window, testCase.expected, answer)
+		}
+	}
+}
+
+func TestChangesShortHistory(t *testing.T) {
+	end, _ := date.ParseDay("2024-09-13")
+	start, _ := date.ParseDay("2024-09-09")
+
+	history := candles(start, end, func(day time.Time) float64 { return 10 })
+
+	changes := Changes(history, end)
+
+	if _, ok := changes["1d"]; !ok {
+		t.Errorf("Expected a 1d change")
+	}
+	if _, ok := changes["1m"]; ok {
+		t.Errorf("Expected no 1m change, got %f", changes["1m"])
+	}
+}
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/erikbryant/options/cache"
+	"github.com/erikbryant/options/date"
+	"github.com/erikbryant/options/finnhub"
+	"github.com/erikbryant/options/gdrive"
+	"github.com/erikbryant/options/marketData"
+	"github.com/erikbryant/options/options"
+	"github.com/erikbryant/options/positions"
+	"github.com/erikbryant/options/schedule"
+	"github.com/erikbryant/options/security"
+	"github.com/erikbryant/options/skiplist"
+	"github.com/erikbryant/options/universe"
+)
+
+// Exit codes, for scripts
+const (
+	exitOK      = 0
+	exitFailure = 1 // the command failed
+	exitUsage   = 2 // the command line was wrong (as for a bad flag)
+)
+
+// usageError is a mistake in the command line
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// command is a subcommand of the CLI
+type command struct {
+	name      string // one or two words, e.g. "cache stats"
+	args      string // the arguments it takes, for the usage
+	summary   string
+	providers bool // it needs the market data providers, so the passphrase
+	run       func(args []string) error
+}
+
+// commands are the subcommands, in the order the usage lists them
+var commands = []command{
+	{"scan", "", "Find the option plays and publish each profile's sheets", true, func(args []string) error {
+		return scan(latest(), false)
+	}},
+	{"chain", "TICKER", "Print a ticker's option chain by expiration and strike", true, func(args []string) error {
+		return chain(args[0])
+	}},
+	{"quote", "TICKER", "Print a ticker's price, P/E, dividend yield and earnings date", true, func(args []string) error {
+		return quote(args[0])
+	}},
+	{"positions", "", "Print what we hold, with the underlyings' prices", true, func(args []string) error {
+		return listPositions()
+	}},
+	{"precache", "", "Refresh the universe's candle history, to spread out the API quota", true, func(args []string) error {
+		return precache()
+	}},
+	{"cache stats", "", "Print how many web requests are cached, and how old they are", false, func(args []string) error {
+		return cacheStats()
+	}},
+	{"cache purge", "", "Delete cached web requests older than -maxAge", false, func(args []string) error {
+		removed, err := cache.Purge(*maxAge, time.Now())
+		fmt.Printf("Purged %d cached requests\n", removed)
+		return err
+	}},
+	{"upload", "FILE", "Publish a sheet to its profile's targets", false, func(args []string) error {
+		return uploadFile(args[0])
+	}},
+	{"profile validate", "", "Check the profiles, skip rules and publishers", false, func(args []string) error {
+		return validate()
+	}},
+	{"serve", "ADDR", "Serve the last scan's securities over HTTP (e.g. on :8080), without the network", false, func(args []string) error {
+		return serve(args[0])
+	}},
+	{"daemon", "SCHEDULE", "Run the scheduled jobs (precache, scans, alerts) as they come due", true, func(args []string) error {
+		return daemon(args[0])
+	}},
+	{"schedule", "SCHEDULE", "Print when each scheduled job runs next", false, func(args []string) error {
+		return printSchedule(args[0])
+	}},
+}
+
+func usage() {
+	fmt.Println("Usage:")
+	fmt.Println()
+	fmt.Println("  options COMMAND [ARGS] [FLAGS]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	for _, c := range commands {
+		fmt.Printf("  %-26s %s\n", strings.TrimSpace(c.name+" "+c.args), c.summary)
+	}
+	fmt.Println()
+	fmt.Println("Flags:")
+	flag.PrintDefaults()
+	fmt.Println()
+	fmt.Printf("Exits %d on success, %d if the command failed and %d if the command line was wrong.\n", exitOK, exitFailure, exitUsage)
+	fmt.Println()
+	fmt.Println("Example:")
+	fmt.Println("  options scan -passPhrase XYZZY -expiration 2021-11-19")
+}
+
+// parse parses the flags, wherever they are among args, and returns the
+// rest. It exits if a flag is wrong.
+func parse(args []string) []string {
+	rest := []string{}
+	for {
+		flag.CommandLine.Parse(args)
+		args = flag.Args()
+		if len(args) == 0 {
+			return rest
+		}
+		rest = append(rest, args[0])
+		args = args[1:]
+	}
+}
+
+// find returns the command args name and the arguments that follow it
+func find(args []string) (command, []string, bool) {
+	for _, c := range commands {
+		words := strings.Fields(c.name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == c.name {
+			return c, args[len(words):], true
+		}
+	}
+	return command{}, nil, false
+}
+
+// run runs the command args name and returns the exit code
+func run(args []string) int {
+	if len(args) == 0 {
+		usage()
+		return exitUsage
+	}
+	if args[0] == "help" {
+		usage()
+		return exitOK
+	}
+
+	c, rest, ok := find(args)
+	if !ok {
+		fmt.Printf("Unknown command %s\n\n", strings.Join(args, " "))
+		usage()
+		return exitUsage
+	}
+
+	err := check(c, rest)
+	if err == nil {
+		err = c.run(rest)
+	}
+
+	var mistake usageError
+	if errors.As(err, &mistake) {
+		fmt.Println(err)
+		fmt.Printf("Usage: options %s\n", strings.TrimSpace(c.name+" "+c.args))
+		return exitUsage
+	}
+	if err != nil {
+		fmt.Println(err)
+		return exitFailure
+	}
+
+	return exitOK
+}
+
+// check checks the command line for the command and readies the providers
+// it needs
+func check(c command, args []string) error {
+	if want := len(strings.Fields(c.args)); len(args) != want {
+		return usageError(fmt.Sprintf("wrong number of arguments for %s", c.name))
+	}
+
+	if *expiration != "" {
+		if _, err := date.ParseDay(*expiration); err != nil {
+			return usageError(fmt.Sprintf("bad -expiration %s", err))
+		}
+	}
+
+	gdrive.Init(*googleCred, *googleTok, !*noLogin)
+
+	if !c.providers {
+		return nil
+	}
+	if *passPhrase == "" {
+		return usageError("you must specify a passPhrase")
+	}
+	return marketData.Init(*passPhrase)
+}
+
+// latest returns the latest expiration to look at: -expiration, or else the
+// next to close
+func latest() string {
+	if *expiration != "" {
+		return *expiration
+	}
+	return schedule.Expiration(time.Now(), 1)
+}
+
+// chain prints the ticker's option chain, through the latest expiration
+func chain(ticker string) error {
+	ticker = strings.ToUpper(ticker)
+	expiration := latest()
+	err := finnhub.Init(*passPhrase, expiration)
+	if err != nil {
+		return err
+	}
+
+	// Look at it whether or not the skip rules would
+	securities, err := options.Securities([]string{ticker}, expiration, math.Inf(1), &skiplist.Rules{})
+	if err != nil {
+		return err
+	}
+	if len(securities) == 0 {
+		return fmt.Errorf("no options for %s through %s", ticker, expiration)
+	}
+	sec := securities[0]
+
+	fmt.Printf("\r%s %s $%0.2f\n", sec.Ticker, sec.Name, sec.Price)
+
+	// Line up the calls and puts by expiration and strike
+	type strikes map[float64][2]*security.Contract
+	byExpiration := map[string]strikes{}
+	for side, contracts := range [][]security.Contract{sec.Calls, sec.Puts} {
+		for i := range contracts {
+			c := &contracts[i]
+			if byExpiration[c.Expiration] == nil {
+				byExpiration[c.Expiration] = strikes{}
+			}
+			pair := byExpiration[c.Expiration][c.Strike]
+			pair[side] = c
+			byExpiration[c.Expiration][c.Strike] = pair
+		}
+	}
+
+	expirations := []string{}
+	for e := range byExpiration {
+		expirations = append(expirations, e)
+	}
+	sort.Strings(expirations)
+
+	cells := func(c *security.Contract) string {
+		if c == nil {
+			return fmt.Sprintf("%7s %7s %6s %6s", "", "", "", "")
+		}
+		return fmt.Sprintf("%7.2f %7.2f %6.2f %6.1f", c.Bid, c.Ask, c.Delta, c.IV)
+	}
+
+	for _, e := range expirations {
+		fmt.Printf("\n%s            calls             |          puts\n", e)
+		fmt.Printf("%7s %7s %6s %6s %8s %7s %7s %6s %6s\n", "Bid", "Ask", "Delta", "IV", "Strike", "Bid", "Ask", "Delta", "IV")
+
+		prices := []float64{}
+		for strike := range byExpiration[e] {
+			prices = append(prices, strike)
+		}
+		sort.Float64s(prices)
+
+		marked := false
+		for _, strike := range prices {
+			if !marked && strike >= sec.Price {
+				fmt.Printf("%s %8.2f\n", strings.Repeat("-", 29), sec.Price)
+				marked = true
+			}
+			pair := byExpiration[e][strike]
+			fmt.Printf("%s %8.2f %s\n", cells(pair[0]), strike, cells(pair[1]))
+		}
+	}
+
+	return nil
+}
+
+// quote prints the ticker's price and what we know of it
+func quote(ticker string) error {
+	err := finnhub.Init(*passPhrase, latest())
+	if err != nil {
+		return err
+	}
+
+	sec := security.Security{Ticker: strings.ToUpper(ticker)}
+	err = finnhub.GetStock(&sec)
+	if err != nil {
+		return err
+	}
+	if sec.Price == 0 {
+		return fmt.Errorf("no current price for %s", sec.Ticker)
+	}
+
+	err = finnhub.GetProfile(&sec)
+	if err != nil {
+		fmt.Println(err)
+	}
+	sec.EarningsDate = finnhub.Earnings(sec.Ticker)
+
+	fmt.Printf("%s %s\n", sec.Ticker, sec.Name)
+	fmt.Printf("  Price          %0.2f\n", sec.Price)
+	fmt.Printf("  P/E            %0.2f\n", sec.PE)
+	fmt.Printf("  Dividend yield %0.2f%%\n", sec.DividendYield)
+	if sec.EarningsDate != "" {
+		fmt.Printf("  Earnings       %s\n", sec.EarningsDate)
+	}
+
+	return nil
+}
+
+// listPositions prints what we hold, with the underlyings' current prices
+// and, for options, whether they are in the money
+func listPositions() error {
+	held, err := positions.Load(universe.PositionsFile)
+	if err != nil {
+		return err
+	}
+	if len(held) == 0 {
+		fmt.Printf("No positions in %s\n", universe.PositionsFile)
+		return nil
+	}
+
+	err = finnhub.Init(*passPhrase, latest())
+	if err != nil {
+		return err
+	}
+
+	prices := map[string]float64{}
+	for _, ticker := range positions.Tickers(held) {
+		sec := security.Security{Ticker: ticker}
+		err := finnhub.GetStock(&sec)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		prices[ticker] = sec.Price
+	}
+
+	now := time.Now()
+	for _, p := range held {
+		line := fmt.Sprintf("  %-6s %-5s %8.0f", p.Ticker, p.Type, p.Quantity)
+		if p.Type != positions.Stock {
+			line += fmt.Sprintf(" %s %8.2f", p.Expiration, p.Strike)
+		}
+		price, ok := prices[p.Ticker]
+		if !ok {
+			fmt.Println(line)
+			continue
+		}
+		line += fmt.Sprintf("  price %8.2f", price)
+		if p.Type != positions.Stock {
+			itm := (p.Type == positions.Put && p.Strike > price) || (p.Type == positions.Call && p.Strike < price)
+			moneyness := "OTM"
+			if itm {
+				moneyness = "ITM"
+			}
+			line += "  " + moneyness
+			if expiration, err := date.ParseDay(p.Expiration); err == nil {
+				line += fmt.Sprintf(", %d days left", date.DaysBetween(now, expiration))
+			}
+		}
+		fmt.Println(line)
+	}
+
+	return nil
+}
+
+// cacheStats prints what the web request cache holds
+func cacheStats() error {
+	stats, err := cache.GetStats()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d cached requests, %0.1f MB\n", stats.Entries, float64(stats.Bytes)/(1024*1024))
+	if stats.Entries > 0 {
+		now := time.Now()
+		fmt.Printf("Oldest %s ago, newest %s ago\n", now.Sub(stats.Oldest).Round(time.Minute), now.Sub(stats.Newest).Round(time.Minute))
+	}
+
+	return nil
+}
+
+// uploadFile publishes the sheet to its profile's targets. The profile and
+// expiration come from the sheet's name (e.g. eb_2024-06-14_puts.csv) unless
+// given by -profile and -expiration.
+func uploadFile(file string) error {
+	if _, err := os.Stat(file); err != nil {
+		return fmt.Errorf("unable to read sheet %s", err)
+	}
+
+	initials, expiration := *profileName, *expiration
+	parts := strings.SplitN(strings.TrimSuffix(filepath.Base(file), ".csv"), "_", 3)
+	if len(parts) == 3 {
+		if initials == "" {
+			initials = parts[0]
+		}
+		if expiration == "" {
+			expiration = parts[1]
+		}
+	}
+	if initials == "" {
+		return usageError(fmt.Sprintf("cannot tell the profile from the name %s; give it with -profile", file))
+	}
+	if expiration == "" {
+		expiration = latest()
+	}
+
+	c, err := loadConfig(expiration)
+	if err != nil {
+		return err
+	}
+
+	var p *security.Params
+	for i := range c.params {
+		if c.params[i].Initials == initials {
+			p = &c.params[i]
+		}
+	}
+	if p == nil {
+		return usageError(fmt.Sprintf("no profile %s", initials))
+	}
+
+	d, manifestPath, err := drive()
+	if err != nil {
+		return err
+	}
+	manifest, err := gdrive.LoadManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, target := range targets(*p) {
+		publisher := c.publishers[target]
+		if target == "drive" {
+			publisher = drivePublisher{d, *p, manifest, expiration}
+		}
+		err := publisher.Publish(file)
+		if err != nil {
+			fmt.Println(err)
+			failed++
+			continue
+		}
+		fmt.Printf("Published %s to %s\n", file, target)
+	}
+
+	err = manifest.Save(manifestPath)
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%s failed to publish to %d targets", file, failed)
+	}
+
+	return nil
+}
+
+// validate checks the profiles, the skip rules and the publishers
+func validate() error {
+	c, err := loadConfig(latest())
+	if err != nil {
+		return err
+	}
+
+	for _, p := range c.params {
+		fmt.Printf("Profile %s: ok (publishes to %s, %d alerts)\n", p.Initials, strings.Join(targets(p), ", "), len(p.Alerts))
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// flagValue returns the named flag's value
+func flagValue(name string) string {
+	return flag.Lookup(name).Value.String()
+}
+
+// setFlag sets the named flag
+func setFlag(name, value string) {
+	flag.Set(name, value)
+}
+
+// restore puts the flags back once the test is done
+func restore(t *testing.T) {
+	saved := map[string]string{}
+	for _, name := range []string{"passPhrase", "expiration", "daemonState", "profile"} {
+		saved[name] = flagValue(name)
+	}
+	t.Cleanup(func() {
+		for name, value := range saved {
+			setFlag(name, value)
+		}
+	})
+}
+
+func TestParse(t *testing.T) {
+	restore(t)
+
+	testCases := []struct {
+		args       []string
+		expected   []string
+		expiration string
+	}{
+		{[]string{"scan"}, []string{"scan"}, ""},
+		{[]string{"-expiration", "2024-06-14", "scan"}, []string{"scan"}, "2024-06-14"},
+		{[]string{"chain", "KO", "-expiration", "2024-06-21"}, []string{"chain", "KO"}, "2024-06-21"},
+		{[]string{"cache", "-expiration=2024-06-28", "purge"}, []string{"cache", "purge"}, "2024-06-28"},
+		{[]string{}, []string{}, ""},
+	}
+
+	for _, testCase := range testCases {
+		setFlag("expiration", "")
+		answer := parse(testCase.args)
+		if !slices.Equal(answer, testCase.expected) || flagValue("expiration") != testCase.expiration {
+			t.Errorf("ERROR: For %v expected %v %q, got %v %q", testCase.args, testCase.expected, testCase.expiration, answer, flagValue("expiration"))
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	testCases := []struct {
+		args     []string
+		expected string
+		rest     []string
+		ok       bool
+	}{
+		{[]string{"scan"}, "scan", []string{}, true},
+		{[]string{"chain", "KO"}, "chain", []string{"KO"}, true},
+		{[]string{"cache", "stats"}, "cache stats", []string{}, true},
+		{[]string{"cache", "purge", "extra"}, "cache purge", []string{"extra"}, true},
+		{[]string{"profile", "validate"}, "profile validate", []string{}, true},
+		{[]string{"cache"}, "", nil, false},
+		{[]string{"profile"}, "", nil, false},
+		{[]string{"frobnicate"}, "", nil, false},
+	}
+
+	for _, testCase := range testCases {
+		c, rest, ok := find(testCase.args)
+		if c.name != testCase.expected || !slices.Equal(rest, testCase.rest) || ok != testCase.ok {
+			t.Errorf("ERROR: For %v expected %q %v %v, got %q %v %v", testCase.args, testCase.expected, testCase.rest, testCase.ok, c.name, rest, ok)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	restore(t)
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	schedule := filepath.Join(dir, "schedule.json")
+	err := os.WriteFile(schedule, []byte(`{"jobs": [{"name": "sheets", "task": "scan", "cron": "30 16 * * 5"}]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setFlag("daemonState", filepath.Join(dir, "daemon-state.json"))
+
+	testCases := []struct {
+		args     []string
+		expected int
+	}{
+		{[]string{}, exitUsage},
+		{[]string{"help"}, exitOK},
+		{[]string{"frobnicate"}, exitUsage},
+		{[]string{"schedule", schedule}, exitOK},
+		{[]string{"schedule"}, exitUsage},
+		{[]string{"schedule", schedule, "extra"}, exitUsage},
+		{[]string{"cache", "stats", "extra"}, exitUsage},
+		{[]string{"schedule", filepath.Join(dir, "missing.json")}, exitFailure},
+		{[]string{"schedule", schedule, "-expiration", "2024-13-01"}, exitUsage},
+		{[]string{"quote", "KO"}, exitUsage},
+		{[]string{"quote", "KO", "-passPhrase", "wrong"}, exitFailure},
+		{[]string{"upload", "-profile", "", filepath.Join(dir, "sheet.csv")}, exitFailure},
+	}
+
+	for _, testCase := range testCases {
+		setFlag("passPhrase", "")
+		setFlag("expiration", "")
+		answer := run(parse(testCase.args))
+		if answer != testCase.expected {
+			t.Errorf("ERROR: For %v expected %d, got %d", testCase.args, testCase.expected, answer)
+		}
+	}
+}
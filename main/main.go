@@ -3,64 +3,182 @@ package main
 import (
 	"flag"
 	"fmt"
-	"github.com/erikbryant/options/skiplist"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
-	"github.com/erikbryant/options/cboe"
+	"github.com/erikbryant/options/alerts"
+	"github.com/erikbryant/options/api"
+	"github.com/erikbryant/options/cache"
+	"github.com/erikbryant/options/date"
+	"github.com/erikbryant/options/dividends"
+	"github.com/erikbryant/options/fills"
 	"github.com/erikbryant/options/finnhub"
 	"github.com/erikbryant/options/gdrive"
-	"github.com/erikbryant/options/marketData"
+	"github.com/erikbryant/options/history"
 	"github.com/erikbryant/options/options"
+	"github.com/erikbryant/options/publish"
+	"github.com/erikbryant/options/report"
+	"github.com/erikbryant/options/schedule"
+	"github.com/erikbryant/options/screen"
 	"github.com/erikbryant/options/security"
-	"github.com/erikbryant/options/utils"
+	"github.com/erikbryant/options/sizing"
+	"github.com/erikbryant/options/skiplist"
+	"github.com/erikbryant/options/universe"
 )
 
 var (
 	passPhrase = flag.String("passPhrase", "", "Passphrase to unlock API key(s)")
-	expiration = flag.String("expiration", "", "Only options up to this expiration")
+	expiration = flag.String("expiration", "", "Only options up to this expiration (default this week's, or next week's once it closes)")
+	skipFile   = flag.String("skiplist", "skiplist.json", "Rules for securities we do not want to trade in")
+	tickerSet  = flag.String("universe", "cboe", "Tickers to scan, e.g. 'cboe + watchlist:eb - holdings'")
+	replay     = flag.String("replayUniverse", "", "Scan the universe saved on this date (YYYY-MM-DD) instead")
+	reportFile = flag.String("report", "run-report.json", "Where to save the run report")
+	inPlace    = flag.Bool("inPlace", false, "Update existing sheets in place, keeping their lots and notes")
+	googleCred = flag.String("googleCredentials", "credentials.json", "Google OAuth client or service account credentials")
+	googleTok  = flag.String("googleToken", "token.json", "Where the Google OAuth token is kept")
+	noLogin    = flag.Bool("nonInteractive", false, "Fail rather than ask for a Google login")
+	// The Google Drive ID of the folder to upload to, for profiles that do not have their own
+	sharedFolder = flag.String("folder", "1BpXjfOqRaSnpv0peBNzA8GcudX2-KMH3", "Google Drive folder ID to upload to")
+	manifestFile = flag.String("manifest", "upload-manifest.json", "Local record of the uploaded sheets")
+	dryRun       = flag.String("dry-run", "", "Upload to this local directory instead of Google Drive")
+	publishFile  = flag.String("publishers", "publishers.json", "Where else profiles may publish their sheets")
+	alertLog     = flag.String("alertLog", "alerts-sent.json", "Record of the contracts we alerted on, so we do not repeat them")
+	stateFile    = flag.String("daemonState", "daemon-state.json", "Where the daemon records its runs")
+	serveDate    = flag.String("snapshot", "", "Serve the securities saved on this date (YYYY-MM-DD) rather than the latest")
+	maxAge       = flag.Duration("maxAge", 72*time.Hour, "Purge cached web requests older than this")
+	profileName  = flag.String("profile", "", "The profile a sheet to upload belongs to (default from its name)")
 )
 
-func usage() {
-	fmt.Println("Usage:")
-	fmt.Println()
-	fmt.Println("  Find all option plays")
-	fmt.Println("    options -passPhrase XYZZY -expiration 2021-11-19")
+// drivePublisher publishes a profile's sheets to its Google Drive folder
+type drivePublisher struct {
+	d          gdrive.Drive
+	p          security.Params
+	manifest   *gdrive.Manifest
+	expiration string
 }
 
-func upload(sheet, parentID string) {
-	_, err := gdrive.CreateSheet(sheet, parentID)
+// Publish uploads the sheet to the profile's Drive folder and records it in the manifest
+func (dp drivePublisher) Publish(sheet string) error {
+	d, p, manifest := dp.d, dp.p, dp.manifest
+
+	folderID := p.Folder
+	if folderID == "" {
+		folderID = *sharedFolder
+	}
+
+	subfolder, err := gdrive.FolderName(p.Subfolders, dp.expiration)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return err
+	}
+	if subfolder != "" {
+		folderID, err = d.Folder(subfolder, folderID)
+		if err != nil {
+			return err
+		}
+	}
+
+	var id string
+	if *inPlace {
+		id, err = d.UpdateSheet(sheet, folderID, security.HeaderRows, gdrive.Preserved(p.Cash > 0))
+	} else {
+		id, err = d.CreateSheet(sheet, folderID)
 	}
-	fmt.Println("Uploaded", sheet)
+	if err != nil {
+		return err
+	}
+
+	manifest.Add(gdrive.Upload{Profile: p.Initials, Name: sheet, ID: id, FolderID: folderID, Uploaded: time.Now()})
+	return nil
 }
 
-func main() {
-	flag.Parse()
+// targets returns the names of the places the profile publishes to
+func targets(p security.Params) []string {
+	if len(p.Publish) == 0 {
+		return []string{"drive"}
+	}
+	return p.Publish
+}
+
+// upload publishes the sheet to each of the profile's targets, or if it is
+// empty skips it (or publishes a placeholder, if the profile asks for one),
+// and records which in the run report
+func upload(publishers map[string]publish.Publisher, sheet security.Sheet, p security.Params, expiration string) {
+	result := report.Sheet{Profile: p.Initials, Name: sheet.Name, Rows: sheet.Rows, Outcome: report.Uploaded}
+
+	if sheet.Rows == 0 {
+		if !p.Placeholders {
+			fmt.Printf("No matches for %s; not uploading it\n", sheet.Name)
+			result.Outcome = report.Skipped
+			report.AddSheet(result)
+			return
+		}
+		result.Outcome = report.Placeholder
+		err := security.Placeholder(sheet, expiration, p)
+		if err != nil {
+			result.Outcome = report.Failed
+			result.Error = err.Error()
+			fmt.Println(err)
+			report.AddSheet(result)
+			return
+		}
+	}
 
-	if *passPhrase == "" {
-		fmt.Println("You must specify a passPhrase")
-		usage()
-		return
+	failures := []string{}
+	for _, target := range targets(p) {
+		err := publishers[target].Publish(sheet.Name)
+		if err != nil {
+			fmt.Println(err)
+			failures = append(failures, fmt.Sprintf("%s: %s", target, err))
+			continue
+		}
+		result.Published = append(result.Published, target)
+		fmt.Printf("Published %s (%d rows) to %s\n", sheet.Name, sheet.Rows, target)
 	}
 
-	if *expiration == "" {
-		fmt.Println("You must specify an expiration")
-		usage()
-		return
+	if len(failures) > 0 {
+		result.Outcome = report.Failed
+		result.Error = strings.Join(failures, "; ")
 	}
 
-	finnhub.Init(*passPhrase, *expiration)
-	marketData.Init(*passPhrase)
+	report.AddSheet(result)
+}
 
-	// Construct the list of options to scan
-	weekly, err := cboe.WeeklyOptions()
+// config is the configuration the commands share
+type config struct {
+	rules      *skiplist.Rules
+	publishers map[string]publish.Publisher
+	params     []security.Params
+}
+
+// loadConfig loads the skip rules, the publishers and the profiles, checked
+// for scanning through the given expiration
+func loadConfig(expiration string) (config, error) {
+	var c config
+	var err error
+
+	c.rules, err = skiplist.Load(*skipFile)
 	if err != nil {
-		fmt.Printf("error loading CBOE weekly options list %s\n", err)
-		return
+		return c, err
 	}
-	weekly = utils.Remove(weekly, skiplist.Skip)
 
+	c.publishers, err = publish.Load(*publishFile)
+	if err != nil {
+		return c, err
+	}
+
+	c.params, err = profiles(expiration, c.publishers)
+	if err != nil {
+		return c, err
+	}
+
+	return c, nil
+}
+
+// profiles returns the profiles, checked and ready to scan with
+func profiles(expiration string, publishers map[string]publish.Publisher) ([]security.Params, error) {
 	params := []security.Params{
 		{
 			Initials:        "cc",
@@ -71,8 +189,8 @@ func main() {
 			MinCallSpread:   0.0,
 			MinIfCalled:     0.0,
 			Itm:             true,
-			CallCols:        []string{"ticker", "expiration", "price", "priceChange", "strike", "last", "bid", "ask", "bidPriceRatio", "ifCalled", "delta", "IV", "safetySpread", "callSpread", "age", "earnings", "pe", "lotSize", "notes", "otmItm", "KellyCriterion", "lots", "premium", "outlay"},
-			PutCols:         []string{"ticker", "expiration", "price", "priceChange", "strike", "last", "bid", "ask", "bidStrikeRatio", "delta", "IV", "safetySpread", "callSpread", "age", "earnings", "pe", "lotSize", "notes", "otmItm", "KellyCriterion", "lots", "premium", "exposure"},
+			CallCols:        []string{"ticker", "expiration", "price", "priceChange", "strike", "last", "bid", "ask", "bidPriceRatio", "ifCalled", "ifCalledDiv", "exDividend", "dividend", "earlyAssignment", "delta", "IV", "safetySpread", "callSpread", "age", "earnings", "pe", "lotSize", "notes", "otmItm", "KellyCriterion", "lots", "held", "premium", "outlay"},
+			PutCols:         []string{"ticker", "expiration", "price", "priceChange", "strike", "last", "bid", "ask", "bidStrikeRatio", "delta", "IV", "safetySpread", "callSpread", "age", "earnings", "pe", "lotSize", "notes", "otmItm", "KellyCriterion", "lots", "held", "premium", "exposure"},
 		},
 		{
 			Initials:        "eb",
@@ -88,6 +206,90 @@ func main() {
 		},
 	}
 
+	// Check each profile's settings, learning our fill model from past fills if asked
+	for i := range params {
+		err := params[i].Fill.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %s", params[i].Initials, err)
+		}
+		err = params[i].Fees.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %s", params[i].Initials, err)
+		}
+		_, err = gdrive.FolderName(params[i].Subfolders, expiration)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %s", params[i].Initials, err)
+		}
+		for _, rule := range params[i].Alerts {
+			err = rule.Validate()
+			if err != nil {
+				return nil, fmt.Errorf("profile %s: %s", params[i].Initials, err)
+			}
+		}
+		for _, target := range targets(params[i]) {
+			if _, ok := publishers[target]; !ok && target != "drive" {
+				return nil, fmt.Errorf("profile %s: unknown publisher %s", params[i].Initials, target)
+			}
+		}
+		if params[i].Fill.Kind != security.FillLearned {
+			continue
+		}
+		params[i].Fill.Fraction, params[i].Fill.Samples, err = fills.Learn("fills.csv")
+		if err != nil || params[i].Fill.Samples == 0 {
+			fmt.Printf("Profile %s: unable to learn fill model (%v); assuming we fill at the bid\n", params[i].Initials, err)
+			params[i].Fill = security.FillModel{Kind: security.FillBid}
+		}
+	}
+
+	return params, nil
+}
+
+// drive returns where to upload sheets, Google Drive or the -dry-run
+// directory, and where to keep the manifest of what was uploaded
+func drive() (gdrive.Drive, string, error) {
+	if *dryRun == "" {
+		return gdrive.Google{}, *manifestFile, nil
+	}
+
+	local, err := gdrive.NewLocal(*dryRun)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Keep the real manifest out of it
+	return local, filepath.Join(*dryRun, filepath.Base(*manifestFile)), nil
+}
+
+// scan finds the option plays expiring through the given expiration and
+// evaluates each profile's alerts. Unless alertsOnly, it also publishes
+// each profile's sheets.
+func scan(expiration string, alertsOnly bool) error {
+	report.Reset()
+	err := finnhub.Init(*passPhrase, expiration)
+	if err != nil {
+		return err
+	}
+
+	today := date.Format(time.Now())
+
+	err = dividends.Init("dividends.csv", today)
+	if err != nil {
+		return fmt.Errorf("error loading dividend calendar %s", err)
+	}
+
+	c, err := loadConfig(expiration)
+	if err != nil {
+		return err
+	}
+	rules, params := c.rules, c.params
+
+	// Construct the list of options to scan
+	tickers, err := universe.Tickers(*tickerSet, today, *replay)
+	if err != nil {
+		return fmt.Errorf("error loading ticker universe %s", err)
+	}
+	tickers = rules.Tickers(tickers, today)
+
 	// Find the max share price we care about; we'll ignore any security above this price
 	maxPrice := 0.0
 	for _, param := range params {
@@ -97,18 +299,198 @@ func main() {
 	}
 
 	// Load underlying data for all options
-	securities, err := options.Securities(weekly, *expiration, maxPrice)
+	securities, err := options.Securities(tickers, expiration, maxPrice, rules)
+	if err != nil {
+		return fmt.Errorf("error getting security: %s", err)
+	}
+
+	// Keep what we loaded, so it can be served later without the network
+	err = options.Save(options.Snapshot{Date: today, Expiration: expiration, Securities: securities})
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	d, manifestPath, err := drive()
+	if err != nil {
+		return err
+	}
+
+	manifest, err := gdrive.LoadManifest(manifestPath)
 	if err != nil {
-		fmt.Println("Error getting security:", err)
-		return
+		return err
 	}
 
-	// The Google Drive ID of the folder to upload to
-	parentID := "1BpXjfOqRaSnpv0peBNzA8GcudX2-KMH3"
+	sent, err := alerts.LoadLog(*alertLog)
+	if err != nil {
+		return err
+	}
 
 	for _, param := range params {
-		putsSheet, callsSheet := security.Print(securities, *expiration, param)
-		upload(putsSheet, parentID)
-		upload(callsSheet, parentID)
+		skip := func(selections []security.Selection) []security.Selection {
+			return rules.Selections(selections, param.Initials, today)
+		}
+		puts, calls, summary := screen.Run(securities, expiration, param, "", skip)
+		report.PrintExclusions(param.Initials)
+
+		if param.Cash > 0 {
+			fmt.Printf("Profile %s sizing: %s\n", param.Initials, summary)
+		}
+		for _, warning := range sizing.Concentration(param, puts, calls) {
+			fmt.Printf("WARNING: profile %s: %s\n", param.Initials, warning)
+		}
+
+		err = alerts.Evaluate(sent, param.Initials, param.Alerts, append(puts, calls...), time.Now())
+		if err != nil {
+			fmt.Println(err)
+		}
+
+		if alertsOnly {
+			continue
+		}
+
+		pubs := map[string]publish.Publisher{}
+		for name, p := range c.publishers {
+			pubs[name] = p
+		}
+		pubs["drive"] = drivePublisher{d, param, manifest, expiration}
+
+		putsSheet, callsSheet := security.Print(puts, calls, expiration, param)
+		upload(pubs, putsSheet, param, expiration)
+		upload(pubs, callsSheet, param, expiration)
+
+		err = manifest.Retain(d, param.Initials, param.RetainWeeks, param.ArchiveFolder, time.Now())
+		if err != nil {
+			fmt.Printf("Profile %s: %s\n", param.Initials, err)
+		}
+	}
+
+	err = sent.Save(*alertLog, time.Now())
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	// Keep the report of the last full run
+	if alertsOnly {
+		return nil
+	}
+
+	err = manifest.Save(manifestPath)
+	if err != nil {
+		fmt.Println(err)
 	}
+
+	fmt.Println("\nSheets:")
+	report.PrintSheets()
+
+	err = report.Save(*reportFile)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, sheet := range report.Current().Sheets {
+		if sheet.Outcome == report.Failed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d sheets failed to publish", failed)
+	}
+
+	return nil
+}
+
+// precache refreshes the candle history of the universe, so the scan only
+// needs to fetch the days since
+func precache() error {
+	rules, err := skiplist.Load(*skipFile)
+	if err != nil {
+		return err
+	}
+
+	// Resolve the universe without saving it; the day's snapshot is the scan's
+	tickers, err := universe.Resolve(*tickerSet)
+	if err != nil {
+		return fmt.Errorf("error loading ticker universe %s", err)
+	}
+	tickers = rules.Tickers(tickers, date.Format(time.Now()))
+
+	history.Precache(tickers, time.Now())
+	return nil
+}
+
+// printSchedule prints when each job in the schedule file runs next and how
+// its last run went
+func printSchedule(file string) error {
+	jobs, err := schedule.Load(file)
+	if err != nil {
+		return err
+	}
+
+	s, err := schedule.LoadState(*stateFile)
+	if err != nil {
+		return err
+	}
+
+	for _, run := range s.Upcoming(jobs, time.Now()) {
+		js := s.Jobs[run.Job.Name]
+		line := fmt.Sprintf("  %-16s %-9s next %s", run.Job.Name, run.Job.Task, run.At.In(date.Eastern()).Format("Mon 2006-01-02 15:04 MST"))
+		if !js.LastRun.IsZero() {
+			line += fmt.Sprintf(", last %s", js.LastRun.In(date.Eastern()).Format("Mon 2006-01-02 15:04"))
+			if js.Error != "" {
+				line += " failed: " + js.Error
+			}
+		}
+		fmt.Println(line)
+	}
+
+	return nil
+}
+
+// daemon runs the jobs in the schedule file as they come due
+func daemon(file string) error {
+	jobs, err := schedule.Load(file)
+	if err != nil {
+		return err
+	}
+
+	return schedule.Daemon(jobs, *stateFile, func(job schedule.Job, at time.Time) error {
+		removed, err := cache.Purge(job.CacheAge(), time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Running %s (%s); purged %d stale cached requests\n", job.Name, job.Task, removed)
+
+		switch job.Task {
+		case schedule.Precache:
+			return precache()
+		case schedule.Alerts:
+			return scan(schedule.Expiration(at, job.ExpirationWeeks), true)
+		}
+		return scan(schedule.Expiration(at, job.ExpirationWeeks), false)
+	})
+}
+
+// serve answers API requests about the saved securities on addr until stopped
+func serve(addr string) error {
+	snapshot, err := options.Load(*serveDate)
+	if err != nil {
+		return err
+	}
+
+	c, err := loadConfig(snapshot.Expiration)
+	if err != nil {
+		return err
+	}
+
+	s := &api.Server{Snapshot: snapshot, Profiles: c.params, Rules: c.rules, ReportFile: *reportFile}
+
+	fmt.Printf("Serving %d securities saved %s (expirations through %s) on %s\n", len(snapshot.Securities), snapshot.Date, snapshot.Expiration, addr)
+	return http.ListenAndServe(addr, s.Handler())
+}
+
+func main() {
+	flag.Usage = usage
+	flag.CommandLine.SetOutput(os.Stdout)
+	os.Exit(run(parse(os.Args[1:])))
 }
@@ -10,6 +10,7 @@ import (
 
 	"github.com/erikbryant/aes"
 	"github.com/erikbryant/options/cache"
+	"github.com/erikbryant/options/date"
 	"github.com/erikbryant/options/security"
 	"github.com/erikbryant/web"
 )
@@ -20,13 +21,15 @@ var (
 )
 
 // Init initializes the internal state of the package
-func Init(passPhrase string) {
+func Init(passPhrase string) error {
 	var err error
 
 	authToken, err = aes.Decrypt(cipherAuthToken, passPhrase)
 	if err != nil {
-		panic("Incorrect passphrase for marketData auth token")
+		return fmt.Errorf("incorrect passphrase for marketData auth token %s", err)
 	}
+
+	return nil
 }
 
 // float64Slice returns []float64 for the given key
@@ -133,6 +136,11 @@ func parseMarketOptions(m map[string]interface{}, sec *security.Security) error
 		return err
 	}
 
+	volume, err := int64Slice(m, "volume")
+	if err != nil {
+		return err
+	}
+
 	updated, err := int64Slice(m, "updated")
 	if err != nil {
 		return err
@@ -152,6 +160,7 @@ func parseMarketOptions(m map[string]interface{}, sec *security.Security) error
 		contract.Delta = delta[i]
 		contract.IV = iv[i]
 		contract.OpenInterest = openInterest[i]
+		contract.Volume = volume[i]
 		contract.Last = last[i]
 
 		t := time.Unix(expiration[i], 0)
@@ -350,15 +359,65 @@ func Candle(symbol, date string) (float64, float64, error) {
 	return o[0].(float64), c[0].(float64), nil
 }
 
-// PctChange returns the %change in share price between open on startDate and close on endDate
-func PctChange(symbol, startDate, endDate string) (float64, error) {
-	o, _, err := Candle(symbol, startDate)
+// parseCandles extracts the daily candles from the raw format
+func parseCandles(m map[string]interface{}, symbol string) ([]security.DayRange, error) {
+	s, ok := m["s"].(string)
+	if !ok || s != "ok" {
+		return nil, fmt.Errorf("marketData returned non-ok status '%v' for %s", m["s"], symbol)
+	}
+
+	o, err := float64Slice(m, "o")
+	if err != nil {
+		return nil, err
+	}
+	h, err := float64Slice(m, "h")
+	if err != nil {
+		return nil, err
+	}
+	l, err := float64Slice(m, "l")
+	if err != nil {
+		return nil, err
+	}
+	c, err := float64Slice(m, "c")
+	if err != nil {
+		return nil, err
+	}
+	v, err := float64Slice(m, "v")
+	if err != nil {
+		return nil, err
+	}
+	t, err := int64Slice(m, "t")
+	if err != nil {
+		return nil, err
+	}
+
+	candles := []security.DayRange{}
+	for i := range t {
+		candles = append(candles, security.DayRange{
+			Date:   date.Format(time.Unix(t[i], 0)),
+			Open:   o[i],
+			High:   h[i],
+			Low:    l[i],
+			Close:  c[i],
+			Volume: v[i],
+		})
+	}
+
+	return candles, nil
+}
+
+// Candles returns the daily candles for a symbol from startDate to endDate, inclusive
+func Candles(symbol, startDate, endDate string) ([]security.DayRange, error) {
+	url := "https://api.marketdata.app/v1/stocks/candles/D/" + symbol + "/?from=" + startDate + "&to=" + endDate
+	response, err := fetch(url)
 	if err != nil {
-		return 0, err
+		return nil, fmt.Errorf("error fetching marketData %s candles %s", symbol, err)
 	}
-	_, c, err := Candle(symbol, endDate)
+
+	candles, err := parseCandles(response, symbol)
 	if err != nil {
-		return 0, err
+		return nil, fmt.Errorf("error parsing marketData %s candles %s", symbol, err)
 	}
-	return 100.0 * (c - o) / o, nil
+
+	return candles, nil
 }
@@ -2,12 +2,20 @@ package options
 
 import (
 	"fmt"
-	"github.com/erikbryant/options/date"
 	"time"
 
+	"github.com/erikbryant/options/cboe"
+	"github.com/erikbryant/options/date"
+	"github.com/erikbryant/options/dividends"
 	"github.com/erikbryant/options/finnhub"
+	"github.com/erikbryant/options/history"
+	"github.com/erikbryant/options/lookback"
 	"github.com/erikbryant/options/marketData"
+	"github.com/erikbryant/options/pricing"
+	"github.com/erikbryant/options/report"
 	"github.com/erikbryant/options/security"
+	"github.com/erikbryant/options/skiplist"
+	"github.com/erikbryant/options/volatility"
 )
 
 // getStock returns stock data for the given security
@@ -15,6 +23,50 @@ func getStock(sec *security.Security) error {
 	return finnhub.GetStock(sec)
 }
 
+// setTimes fills in the contract's last trade age and time to expiration
+func setTimes(contract *security.Contract, now time.Time) {
+	lastClose := date.LastClose(now)
+
+	contract.LastTradeDays = int64(now.Sub(contract.LastTradeDate).Hours() / 24)
+	contract.LastTradeTradingDays = int64(date.TradingDaysBetween(contract.LastTradeDate, lastClose))
+
+	expiration, err := date.ParseDay(contract.Expiration)
+	if err != nil {
+		fmt.Printf("Unable to parse expiration %s %s\n", contract.Expiration, err)
+		return
+	}
+	contract.DaysToExpiration = int64(date.DaysBetween(now, expiration))
+	contract.TradingDaysToExpiration = int64(date.TradingDaysBetween(lastClose, expiration))
+	contract.HoursToExpiration = date.CloseTime(expiration).Sub(now).Hours()
+}
+
+// setPricing prices the contract accounting for the dividend yield and flags
+// calls likely to be exercised early to capture the next dividend
+func setPricing(sec *security.Security, contract *security.Contract, call bool) {
+	years := contract.HoursToExpiration / (24 * 365)
+	contract.Theo = pricing.BlackScholes(call, sec.Price, contract.Strike, years, sec.Yield(), contract.IV)
+
+	if call && sec.DividendBefore(contract.Expiration) {
+		extrinsic := contract.Bid - pricing.Intrinsic(call, sec.Price, contract.Strike)
+		contract.EarlyAssignment = contract.Strike < sec.Price && extrinsic < sec.Dividend
+	}
+}
+
+// setDividend looks up the next dividend, preferring the local calendar
+func setDividend(sec *security.Security) {
+	d, ok := dividends.Next(sec.Ticker)
+	if ok {
+		sec.ExDividendDate = d.ExDate
+		sec.Dividend = d.Amount
+		return
+	}
+
+	err := finnhub.GetDividend(sec)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
 // getOptions accumulates option data for the given ticker and returns it in a security
 func getOptions(sec *security.Security, expiration string) error {
 	// Fetch data
@@ -23,39 +75,68 @@ func getOptions(sec *security.Security, expiration string) error {
 		return fmt.Errorf("error getting options %s %s", sec.Ticker, err)
 	}
 
+	now := time.Now()
+
 	// Synthetic data. Use the index to access the option instead of having
 	// range return the option, since range returns a COPY of the option.
 	for put := range sec.Puts {
-		sec.Puts[put].PriceBasisDelta = sec.Price - (sec.Puts[put].Strike - sec.Puts[put].Bid)
-		sec.Puts[put].LastTradeDays = int64(time.Since(sec.Puts[put].LastTradeDate).Hours() / 24)
-		sec.Puts[put].BidStrikeRatio = sec.Puts[put].Bid / sec.Puts[put].Strike * 100
-		sec.Puts[put].BidPriceRatio = sec.Puts[put].Bid / sec.Price * 100
-		sec.Puts[put].SafetySpread = (sec.Price - (sec.Puts[put].Strike - sec.Puts[put].Bid)) / sec.Price * 100
+		sec.Puts[put] = sec.Filled(sec.Puts[put], security.FillModel{})
+		setTimes(&sec.Puts[put], now)
+		setPricing(sec, &sec.Puts[put], false)
 		sec.Puts[put].CallSpread = sec.CallSpread(sec.Puts[put].Expiration)
 	}
 	for call := range sec.Calls {
-		sec.Calls[call].PriceBasisDelta = sec.Price - (sec.Calls[call].Strike - sec.Calls[call].Bid)
-		sec.Calls[call].LastTradeDays = int64(time.Since(sec.Calls[call].LastTradeDate).Hours() / 24)
-		sec.Calls[call].BidStrikeRatio = sec.Calls[call].Bid / sec.Calls[call].Strike * 100
-		sec.Calls[call].BidPriceRatio = sec.Calls[call].Bid / sec.Price * 100
-		sec.Calls[call].SafetySpread = (sec.Price - (sec.Calls[call].Strike - sec.Calls[call].Bid)) / sec.Price * 100
+		sec.Calls[call] = sec.Filled(sec.Calls[call], security.FillModel{})
+		setTimes(&sec.Calls[call], now)
+		setPricing(sec, &sec.Calls[call], true)
 		sec.Calls[call].CallSpread = sec.CallSpread(sec.Calls[call].Expiration)
 	}
 
 	return nil
 }
 
-// Securities accumulates stock/option data for the given tickers and returns it in a list of sec
-func Securities(tickers []string, expiration string, maxPrice float64) ([]security.Security, error) {
-	startDate := date.Previous(time.Monday)
-	endDate := date.Previous(time.Friday)
-	fmt.Printf("Using startDate: %s, endDate %s for previous week's price %%change\n\n", startDate, endDate)
+// setVolatility fills in the realized and implied volatility measures of the
+// security and records today's IV so future runs can rank against it
+func setVolatility(sec *security.Security, end time.Time) {
+	sec.HV20 = volatility.Realized(sec.Candles, 20)
+	sec.HV30 = volatility.Realized(sec.Candles, 30)
+	sec.HV60 = volatility.Realized(sec.Candles, 60)
+
+	sec.IV = volatility.AtTheMoney(*sec)
+	if sec.IV > 0 {
+		err := history.RecordIV(sec.Ticker, date.Format(end), sec.IV)
+		if err != nil {
+			fmt.Printf("Unable to record IV for %s %s\n", sec.Ticker, err)
+		}
+	}
+
+	ivs := history.IVs(sec.Ticker, end)
+	sec.IVHistoryDays = len(ivs)
+	sec.IVRank = volatility.Rank(sec.IV, ivs)
+	sec.IVPercentile = volatility.Percentile(sec.IV, ivs)
+
+	if sec.HV30 == 0 {
+		return
+	}
+	for put := range sec.Puts {
+		sec.Puts[put].IVHVRatio = sec.Puts[put].IV / sec.HV30
+	}
+	for call := range sec.Calls {
+		sec.Calls[call].IVHVRatio = sec.Calls[call].IV / sec.HV30
+	}
+}
+
+// Securities accumulates stock/option data for the given tickers and returns it in a list of sec.
+// Tickers the skip rules exclude from the whole run are recorded in the run report.
+func Securities(tickers []string, expiration string, maxPrice float64, rules *skiplist.Rules) ([]security.Security, error) {
+	end := lookback.HistoryEnd(time.Now())
+	startDate := lookback.HistoryStart(end)
+	endDate := date.Format(end)
+	fmt.Printf("Using candles from %s to %s for trailing price %%change\n\n", startDate, endDate)
 
 	var securities []security.Security
 
-	statMaxPrice := []string{}
-	statGetFail := []string{}
-	statNoOptions := []string{}
+	today := date.Format(time.Now())
 
 	for _, ticker := range tickers {
 		fmt.Printf("\r%s    ", ticker)
@@ -71,37 +152,55 @@ func Securities(tickers []string, expiration string, maxPrice float64) ([]securi
 
 		if sec.Price >= maxPrice {
 			// fmt.Printf("Skipping %s due to high price %0.2f > %0.2f\n", sec.Ticker, sec.Price, maxPrice)
-			statMaxPrice = append(statMaxPrice, sec.Ticker)
+			report.Exclude(sec.Ticker, "", "price too high")
+			continue
+		}
+
+		err = finnhub.GetProfile(&sec)
+		if err != nil {
+			fmt.Println(err)
+		}
+		if sec.Name == "" {
+			// FinnHub has no profile for ETFs; the CBOE directory names them
+			if r, ok := cboe.Lookup(sec.Ticker); ok {
+				sec.Name = r.Name
+			}
+		}
+
+		if reason, skip := rules.Skip(&sec, "", today); skip {
+			report.Exclude(sec.Ticker, "", reason)
 			continue
 		}
 
 		sec.EarningsDate = finnhub.Earnings(sec.Ticker)
 
+		setDividend(&sec)
+
 		err = getOptions(&sec, expiration)
 		if err != nil {
 			fmt.Printf("Error getting options: %s\n", err)
-			statGetFail = append(statGetFail, sec.Ticker)
+			report.Exclude(sec.Ticker, "", "get failure")
 			continue
 		}
 
 		if !sec.HasOptions() {
 			fmt.Printf("WARNING: %s has no options\n", sec.Ticker)
-			statNoOptions = append(statNoOptions, sec.Ticker)
+			report.Exclude(sec.Ticker, "", "no options")
 			continue
 		}
 
-		sec.PriceChangePct, err = marketData.PctChange(sec.Ticker, startDate, endDate)
+		sec.Candles, err = history.Candles(sec.Ticker, startDate, endDate)
 		if err != nil {
 			fmt.Println(err)
 		}
+		sec.PriceChanges = lookback.Changes(sec.Candles, end)
+		setVolatility(&sec, end)
 
 		securities = append(securities, sec)
 	}
 
 	fmt.Printf("\r%d of %d tickers loaded\n\n", len(securities), len(tickers))
-	fmt.Printf("  Rejected for price too high (%d): %v\n", len(statMaxPrice), statMaxPrice)
-	fmt.Printf("  Rejected for get failure    (%d): %v\n", len(statGetFail), statGetFail)
-	fmt.Printf("  Rejected for no options     (%d): %v\n", len(statNoOptions), statNoOptions)
+	report.PrintExclusions("")
 
 	return securities, nil
 }
@@ -1 +1,106 @@
 package options
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/erikbryant/options/pricing"
+	"github.com/erikbryant/options/security"
+)
+
+func TestSetTimes(t *testing.T) {
+	// Monday morning, 10:00 Eastern
+	now := time.Date(2024, time.September, 9, 14, 0, 0, 0, time.UTC)
+
+	contract := security.Contract{
+		Expiration:    "2024-09-13",
+		LastTradeDate: time.Date(2024, time.September, 6, 19, 0, 0, 0, time.UTC),
+	}
+
+	setTimes(&contract, now)
+
+	if contract.LastTradeDays != 2 {
+		t.Errorf("Expected LastTradeDays 2, got %d", contract.LastTradeDays)
+	}
+	if contract.LastTradeTradingDays != 0 {
+		t.Errorf("Expected LastTradeTradingDays 0, got %d", contract.LastTradeTradingDays)
+	}
+	if contract.DaysToExpiration != 4 {
+		t.Errorf("Expected DaysToExpiration 4, got %d", contract.DaysToExpiration)
+	}
+	if contract.TradingDaysToExpiration != 5 {
+		t.Errorf("Expected TradingDaysToExpiration 5, got %d", contract.TradingDaysToExpiration)
+	}
+	if contract.HoursToExpiration != 102 {
+		t.Errorf("Expected HoursToExpiration 102, got %f", contract.HoursToExpiration)
+	}
+}
+
+func TestSetPricing(t *testing.T) {
+	// A $50 stock paying $0.50 on 2024-09-13, a year of hours from expiration
+	sec := security.Security{Price: 50, Dividend: 0.50, ExDividendDate: "2024-09-13"}
+	years := 1.0
+
+	testCases := []struct {
+		call     bool
+		contract security.Contract
+		early    bool
+	}{
+		// Deep ITM call with less time value than the dividend
+		{true, security.Contract{Expiration: "2024-09-20", Strike: 40, Bid: 10.20, IV: 0.3}, true},
+		// ITM call with more time value than the dividend
+		{true, security.Contract{Expiration: "2024-09-20", Strike: 45, Bid: 6.00, IV: 0.3}, false},
+		// OTM call
+		{true, security.Contract{Expiration: "2024-09-20", Strike: 55, Bid: 0.10, IV: 0.3}, false},
+		// Expires before the ex-date
+		{true, security.Contract{Expiration: "2024-09-06", Strike: 40, Bid: 10.20, IV: 0.3}, false},
+		// Puts are not assigned for the dividend
+		{false, security.Contract{Expiration: "2024-09-20", Strike: 60, Bid: 10.20, IV: 0.3}, false},
+	}
+
+	for _, testCase := range testCases {
+		contract := testCase.contract
+		contract.HoursToExpiration = years * 24 * 365
+		setPricing(&sec, &contract, testCase.call)
+
+		if contract.EarlyAssignment != testCase.early {
+			t.Errorf("ERROR: For %v expected early assignment %v, got %v", testCase.contract, testCase.early, contract.EarlyAssignment)
+		}
+
+		// Priced with the quarterly dividend as a yield: 4 * 0.50 / 50
+		expected := pricing.BlackScholes(testCase.call, 50, contract.Strike, years, 0.04, 0.3)
+		if math.Abs(contract.Theo-expected) > 0.000001 {
+			t.Errorf("ERROR: For %v expected theo %f, got %f", testCase.contract, expected, contract.Theo)
+		}
+		if contract.Theo == pricing.BlackScholes(testCase.call, 50, contract.Strike, years, 0, 0.3) {
+			t.Errorf("ERROR: For %v expected the dividend to change the theo", testCase.contract)
+		}
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	SnapshotDir = t.TempDir()
+
+	_, err := Load("")
+	if err == nil {
+		t.Errorf("Expected an error with nothing saved")
+	}
+
+	for _, day := range []string{"2024-06-07", "2024-06-14", "2024-06-08"} {
+		err = Save(Snapshot{Date: day, Expiration: "2024-06-21", Securities: []security.Security{{Ticker: "KO", Puts: []security.Contract{{Strike: 60}}}}})
+		if err != nil {
+			t.Fatalf("Unexpected error %s", err)
+		}
+	}
+
+	snapshot, err := Load("")
+	if err != nil || snapshot.Date != "2024-06-14" {
+		t.Errorf("Expected the latest snapshot, got %s %v", snapshot.Date, err)
+	}
+
+	snapshot, err = Load("2024-06-08")
+	if err != nil || snapshot.Date != "2024-06-08" || snapshot.Securities[0].Puts[0].Strike != 60 {
+		t.Errorf("Unexpected snapshot %+v %v", snapshot, err)
+	}
+}
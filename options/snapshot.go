@@ -0,0 +1,70 @@
+package options
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/erikbryant/options/security"
+)
+
+// SnapshotDir is where the securities each scan loaded are saved, by date
+var SnapshotDir = "securities-cache"
+
+// Snapshot is the securities a scan loaded, saved so they can be served
+// (and filtered again) without the network
+type Snapshot struct {
+	Date       string              `json:"date"`
+	Expiration string              `json:"expiration"` // the latest expiration loaded
+	Securities []security.Security `json:"securities"`
+}
+
+// snapshotFile returns where the securities for the given day are saved
+func snapshotFile(day string) string {
+	return filepath.Join(SnapshotDir, day+".json")
+}
+
+// Save records the securities loaded on the snapshot's day
+func Save(snapshot Snapshot) error {
+	err := os.MkdirAll(SnapshotDir, 0755)
+	if err != nil {
+		return fmt.Errorf("unable to create %s %s", SnapshotDir, err)
+	}
+
+	s, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("could not marshal securities %s", err)
+	}
+
+	return os.WriteFile(snapshotFile(snapshot.Date), s, 0644)
+}
+
+// Load returns the securities saved on the given day, or on the latest day
+// saved if day is empty
+func Load(day string) (Snapshot, error) {
+	var snapshot Snapshot
+
+	if day == "" {
+		days, err := filepath.Glob(snapshotFile("*"))
+		if err != nil || len(days) == 0 {
+			return snapshot, fmt.Errorf("no securities saved in %s", SnapshotDir)
+		}
+		sort.Strings(days)
+		day = strings.TrimSuffix(filepath.Base(days[len(days)-1]), ".json")
+	}
+
+	contents, err := os.ReadFile(snapshotFile(day))
+	if err != nil {
+		return snapshot, fmt.Errorf("no securities saved for %s %s", day, err)
+	}
+
+	err = json.Unmarshal(contents, &snapshot)
+	if err != nil {
+		return snapshot, fmt.Errorf("unable to unmarshal securities for %s %s", day, err)
+	}
+
+	return snapshot, nil
+}
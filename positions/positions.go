@@ -0,0 +1,107 @@
+package positions
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/erikbryant/options/csv"
+)
+
+// Position types
+const (
+	Stock = "stock"
+	Put   = "put"
+	Call  = "call"
+)
+
+// Position is something we currently hold. Short positions have negative quantities.
+type Position struct {
+	Ticker     string
+	Type       string  // Stock, Put or Call
+	Quantity   float64 // shares or contracts
+	Expiration string  // options only
+	Strike     float64 // options only
+}
+
+// parse returns the positions in the lines. Each line is
+// ticker,type,quantity[,expiration,strike].
+func parse(lines []string) ([]Position, error) {
+	result := []Position{}
+
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		cols := strings.Split(line, ",")
+		for j := range cols {
+			cols[j] = strings.TrimSpace(cols[j])
+		}
+		if len(cols) < 3 {
+			return nil, fmt.Errorf("line %d: expected ticker,type,quantity[,expiration,strike], got '%s'", i+1, line)
+		}
+
+		p := Position{Ticker: strings.ToUpper(cols[0]), Type: strings.ToLower(cols[1])}
+
+		var err error
+		p.Quantity, err = strconv.ParseFloat(cols[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: unable to parse quantity %s", i+1, err)
+		}
+
+		switch p.Type {
+		case Stock:
+		case Put, Call:
+			if len(cols) != 5 {
+				return nil, fmt.Errorf("line %d: %s positions need an expiration and strike, got '%s'", i+1, p.Type, line)
+			}
+			p.Expiration = cols[3]
+			p.Strike, err = strconv.ParseFloat(cols[4], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: unable to parse strike %s", i+1, err)
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unknown position type '%s'", i+1, cols[1])
+		}
+
+		result = append(result, p)
+	}
+
+	return result, nil
+}
+
+// Load returns the positions recorded in file. A missing file means we hold nothing.
+func Load(file string) ([]Position, error) {
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return []Position{}, nil
+	}
+
+	lines, err := csv.GetFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := parse(lines)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s %s", file, err)
+	}
+
+	return result, nil
+}
+
+// Tickers returns the distinct tickers we hold a (non-zero) position in
+func Tickers(positions []Position) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, p := range positions {
+		if p.Quantity == 0 || seen[p.Ticker] {
+			continue
+		}
+		seen[p.Ticker] = true
+		result = append(result, p.Ticker)
+	}
+	return result
+}
@@ -0,0 +1,54 @@
+package positions
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	lines := []string{
+		"# Comments and blank lines are ignored",
+		"",
+		"ko,stock,200",
+		"KO,call,-2,2024-09-20,65",
+		"T,put,-1,2024-09-20,18.5",
+		"PFE,stock,0",
+	}
+
+	positions, err := parse(lines)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	expected := []Position{
+		{Ticker: "KO", Type: Stock, Quantity: 200},
+		{Ticker: "KO", Type: Call, Quantity: -2, Expiration: "2024-09-20", Strike: 65},
+		{Ticker: "T", Type: Put, Quantity: -1, Expiration: "2024-09-20", Strike: 18.5},
+		{Ticker: "PFE", Type: Stock, Quantity: 0},
+	}
+	if !slices.Equal(positions, expected) {
+		t.Errorf("Expected %v, got %v", expected, positions)
+	}
+
+	tickers := Tickers(positions)
+	if !slices.Equal(tickers, []string{"KO", "T"}) {
+		t.Errorf("Expected [KO T], got %v", tickers)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	testCases := []string{
+		"KO,stock",
+		"KO,stock,many",
+		"KO,bond,100",
+		"KO,call,-1",
+		"KO,call,-1,2024-09-20,sixty",
+	}
+
+	for _, testCase := range testCases {
+		_, err := parse([]string{testCase})
+		if err == nil {
+			t.Errorf("ERROR: For %s expected an error", testCase)
+		}
+	}
+}
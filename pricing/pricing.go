@@ -0,0 +1,50 @@
+package pricing
+
+import (
+	"math"
+)
+
+// RiskFreeRate is the annual, continuously compounded risk-free rate used to price contracts
+var RiskFreeRate = 0.045
+
+// cdf returns the standard normal cumulative distribution function at x
+func cdf(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+// BlackScholes returns the Black-Scholes-Merton value of a European option.
+// years is the time to expiration, yield the continuous dividend yield and
+// sigma the annualized volatility (all as fractions, e.g. 0.02 for 2%).
+func BlackScholes(call bool, spot, strike, years, yield, sigma float64) float64 {
+	if spot <= 0 || strike <= 0 {
+		return 0
+	}
+
+	r := RiskFreeRate
+	discountedSpot := spot * math.Exp(-yield*years)
+	discountedStrike := strike * math.Exp(-r*years)
+
+	// At (or past) expiration, or with no volatility, only intrinsic value remains
+	if years <= 0 || sigma <= 0 {
+		if call {
+			return math.Max(0, discountedSpot-discountedStrike)
+		}
+		return math.Max(0, discountedStrike-discountedSpot)
+	}
+
+	d1 := (math.Log(spot/strike) + (r-yield+sigma*sigma/2)*years) / (sigma * math.Sqrt(years))
+	d2 := d1 - sigma*math.Sqrt(years)
+
+	if call {
+		return discountedSpot*cdf(d1) - discountedStrike*cdf(d2)
+	}
+	return discountedStrike*cdf(-d2) - discountedSpot*cdf(-d1)
+}
+
+// Intrinsic returns the value of the option if it were exercised now
+func Intrinsic(call bool, spot, strike float64) float64 {
+	if call {
+		return math.Max(0, spot-strike)
+	}
+	return math.Max(0, strike-spot)
+}
@@ -0,0 +1,61 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBlackScholes(t *testing.T) {
+	// Textbook example: S=42, K=40, r=10%, sigma=20%, T=0.5
+	RiskFreeRate = 0.10
+	defer func() { RiskFreeRate = 0.045 }()
+
+	call := BlackScholes(true, 42, 40, 0.5, 0, 0.2)
+	if math.Abs(call-4.76) > 0.01 {
+		t.Errorf("Expected call 4.76, got %f", call)
+	}
+
+	put := BlackScholes(false, 42, 40, 0.5, 0, 0.2)
+	if math.Abs(put-0.81) > 0.01 {
+		t.Errorf("Expected put 0.81, got %f", put)
+	}
+}
+
+func TestBlackScholesParity(t *testing.T) {
+	testCases := []struct {
+		spot   float64
+		strike float64
+		years  float64
+		yield  float64
+		sigma  float64
+	}{
+		{100, 100, 0.25, 0.0, 0.3},
+		{100, 95, 0.1, 0.03, 0.5},
+		{20, 25, 1.0, 0.06, 0.8},
+	}
+
+	// C - P = S*e^(-qT) - K*e^(-rT)
+	for _, testCase := range testCases {
+		call := BlackScholes(true, testCase.spot, testCase.strike, testCase.years, testCase.yield, testCase.sigma)
+		put := BlackScholes(false, testCase.spot, testCase.strike, testCase.years, testCase.yield, testCase.sigma)
+		expected := testCase.spot*math.Exp(-testCase.yield*testCase.years) - testCase.strike*math.Exp(-RiskFreeRate*testCase.years)
+		if math.Abs((call-put)-expected) > 0.000001 {
+			t.Errorf("For %v expected C-P %f, got %f", testCase, expected, call-put)
+		}
+	}
+}
+
+func TestBlackScholesDividendYield(t *testing.T) {
+	// A dividend lowers the value of a call and raises that of a put
+	call := BlackScholes(true, 50, 50, 0.25, 0, 0.3)
+	callDiv := BlackScholes(true, 50, 50, 0.25, 0.04, 0.3)
+	if callDiv >= call {
+		t.Errorf("Expected dividend to lower call value, got %f >= %f", callDiv, call)
+	}
+
+	put := BlackScholes(false, 50, 50, 0.25, 0, 0.3)
+	putDiv := BlackScholes(false, 50, 50, 0.25, 0.04, 0.3)
+	if putDiv <= put {
+		t.Errorf("Expected dividend to raise put value, got %f <= %f", putDiv, put)
+	}
+}
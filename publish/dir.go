@@ -0,0 +1,32 @@
+package publish
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Dir publishes sheets by copying them into a local (or mounted) directory
+type Dir struct {
+	Path string
+}
+
+// Publish copies the sheet into the directory
+func (d Dir) Publish(sheet string) error {
+	contents, err := os.ReadFile(sheet)
+	if err != nil {
+		return fmt.Errorf("could not read %s %s", sheet, err)
+	}
+
+	err = os.MkdirAll(d.Path, 0755)
+	if err != nil {
+		return fmt.Errorf("could not create %s %s", d.Path, err)
+	}
+
+	err = os.WriteFile(filepath.Join(d.Path, filepath.Base(sheet)), contents, 0644)
+	if err != nil {
+		return fmt.Errorf("could not publish %s to %s %s", sheet, d.Path, err)
+	}
+
+	return nil
+}
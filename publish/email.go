@@ -0,0 +1,122 @@
+package publish
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"fmt"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Email publishes sheets by mailing them as attachments
+type Email struct {
+	Server   string // host:port
+	From     string
+	To       []string
+	Username string // no authentication if empty
+	Password string
+	XLSX     bool // attach an Excel workbook rather than the CSV
+}
+
+// attachment returns the sheet as the file to attach
+func (e Email) attachment(sheet string) (string, string, []byte, error) {
+	contents, err := os.ReadFile(sheet)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("could not read %s %s", sheet, err)
+	}
+
+	name := filepath.Base(sheet)
+	if !e.XLSX {
+		return name, "text/csv", contents, nil
+	}
+
+	reader := csv.NewReader(bytes.NewReader(contents))
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return "", "", nil, fmt.Errorf("could not parse %s %s", sheet, err)
+	}
+
+	workbook, err := XLSX(rows)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	name = strings.TrimSuffix(name, filepath.Ext(name)) + ".xlsx"
+	return name, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", workbook, nil
+}
+
+// message returns the email carrying the attachment
+func (e Email) message(name, contentType string, attachment []byte) ([]byte, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	text, err := w.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(text, "This week's options sheet, %s, is attached.\r\n", name)
+
+	file, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", name)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(attachment)
+	for len(encoded) > 76 {
+		fmt.Fprintf(file, "%s\r\n", encoded[:76])
+		encoded = encoded[76:]
+	}
+	fmt.Fprintf(file, "%s\r\n", encoded)
+
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&msg, "Subject: Options: %s\r\n", name)
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%s\r\n", w.Boundary())
+	fmt.Fprintf(&msg, "\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+// Publish mails the sheet
+func (e Email) Publish(sheet string) error {
+	name, contentType, attachment, err := e.attachment(sheet)
+	if err != nil {
+		return err
+	}
+
+	msg, err := e.message(name, contentType, attachment)
+	if err != nil {
+		return fmt.Errorf("could not build email for %s %s", sheet, err)
+	}
+
+	var auth smtp.Auth
+	if e.Username != "" {
+		host, _, _ := net.SplitHostPort(e.Server)
+		auth = smtp.PlainAuth("", e.Username, e.Password, host)
+	}
+
+	err = smtp.SendMail(e.Server, auth, e.From, e.To, msg)
+	if err != nil {
+		return fmt.Errorf("could not email %s %s", sheet, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package publish
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Publisher delivers a sheet (a CSV file) somewhere people will look at it
+type Publisher interface {
+	Publish(sheet string) error
+}
+
+// Target configures a publisher
+type Target struct {
+	Type string `json:"type"` // dir, s3 or email
+
+	// dir
+	Path string `json:"path,omitempty"`
+
+	// s3
+	Endpoint  string `json:"endpoint,omitempty"` // e.g. https://s3.us-west-2.amazonaws.com
+	Region    string `json:"region,omitempty"`
+	Bucket    string `json:"bucket,omitempty"`
+	Prefix    string `json:"prefix,omitempty"`
+	AccessKey string `json:"accessKey,omitempty"`
+	SecretKey string `json:"secretKey,omitempty"`
+
+	// email
+	Server   string   `json:"server,omitempty"` // host:port
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	Format   string   `json:"format,omitempty"` // csv (the default) or xlsx
+}
+
+// publisher returns the publisher the target configures. Secrets may be
+// given as $VARIABLE to read them from the environment.
+func (t Target) publisher() (Publisher, error) {
+	switch t.Type {
+	case "dir":
+		if t.Path == "" {
+			return nil, fmt.Errorf("dir target needs a path")
+		}
+		return Dir{Path: t.Path}, nil
+	case "s3":
+		if t.Endpoint == "" || t.Bucket == "" || t.Region == "" {
+			return nil, fmt.Errorf("s3 target needs an endpoint, region and bucket")
+		}
+		return &S3{
+			Endpoint:  t.Endpoint,
+			Region:    t.Region,
+			Bucket:    t.Bucket,
+			Prefix:    t.Prefix,
+			AccessKey: os.ExpandEnv(t.AccessKey),
+			SecretKey: os.ExpandEnv(t.SecretKey),
+		}, nil
+	case "email":
+		if t.Server == "" || t.From == "" || len(t.To) == 0 {
+			return nil, fmt.Errorf("email target needs a server, from and to")
+		}
+		if t.Format != "" && t.Format != "csv" && t.Format != "xlsx" {
+			return nil, fmt.Errorf("unknown email attachment format '%s'", t.Format)
+		}
+		return Email{
+			Server:   t.Server,
+			From:     t.From,
+			To:       t.To,
+			Username: os.ExpandEnv(t.Username),
+			Password: os.ExpandEnv(t.Password),
+			XLSX:     t.Format == "xlsx",
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown publish target type '%s'", t.Type)
+}
+
+// Parse returns the publishers configured in contents, by name
+func Parse(contents []byte) (map[string]Publisher, error) {
+	targets := map[string]Target{}
+
+	err := json.Unmarshal(contents, &targets)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal publish targets %s", err)
+	}
+
+	names := []string{}
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	publishers := map[string]Publisher{}
+	for _, name := range names {
+		p, err := targets[name].publisher()
+		if err != nil {
+			return nil, fmt.Errorf("publish target %s: %s", name, err)
+		}
+		publishers[name] = p
+	}
+
+	return publishers, nil
+}
+
+// Load returns the publishers configured in file, by name. A missing file
+// configures none.
+func Load(file string) (map[string]Publisher, error) {
+	contents, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return map[string]Publisher{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read publish targets %s %s", file, err)
+	}
+
+	publishers, err := Parse(contents)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err)
+	}
+
+	return publishers, nil
+}
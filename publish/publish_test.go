@@ -0,0 +1,377 @@
+package publish
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSheet = "Fill: bid,\n,=sum(B4:B9999)\n  Ticker,  Strike\nKO,65.00\nT & CO,18.50\n"
+
+// writeSheet writes the test sheet and returns its path
+func writeSheet(t *testing.T) string {
+	t.Helper()
+	sheet := filepath.Join(t.TempDir(), "eb_2024-06-07_puts.csv")
+	err := os.WriteFile(sheet, []byte(testSheet), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sheet
+}
+
+func TestParse(t *testing.T) {
+	t.Setenv("TEST_SECRET", "shh")
+
+	publishers, err := Parse([]byte(`{
+		"shared": {"type": "dir", "path": "/tmp/sheets"},
+		"bucket": {"type": "s3", "endpoint": "http://localhost:9000", "region": "us-east-1", "bucket": "options", "accessKey": "AK", "secretKey": "$TEST_SECRET"},
+		"mail": {"type": "email", "server": "localhost:25", "from": "a@b", "to": ["c@d"], "format": "xlsx"}
+	}`))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if d, ok := publishers["shared"].(Dir); !ok || d.Path != "/tmp/sheets" {
+		t.Errorf("Unexpected dir publisher %+v", publishers["shared"])
+	}
+	if s, ok := publishers["bucket"].(*S3); !ok || s.SecretKey != "shh" {
+		t.Errorf("Unexpected s3 publisher %+v", publishers["bucket"])
+	}
+	if e, ok := publishers["mail"].(Email); !ok || !e.XLSX {
+		t.Errorf("Unexpected email publisher %+v", publishers["mail"])
+	}
+
+	errorCases := []string{
+		`not json`,
+		`{"x": {"type": "ftp"}}`,
+		`{"x": {"type": "dir"}}`,
+		`{"x": {"type": "s3", "endpoint": "http://localhost:9000"}}`,
+		`{"x": {"type": "email", "server": "localhost:25"}}`,
+		`{"x": {"type": "email", "server": "localhost:25", "from": "a@b", "to": ["c@d"], "format": "pdf"}}`,
+	}
+	for _, errorCase := range errorCases {
+		_, err := Parse([]byte(errorCase))
+		if err == nil {
+			t.Errorf("ERROR: For %s expected an error", errorCase)
+		}
+	}
+}
+
+func TestDir(t *testing.T) {
+	sheet := writeSheet(t)
+	dir := filepath.Join(t.TempDir(), "shared", "sheets")
+
+	err := Dir{Path: dir}.Publish(sheet)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	contents, err := os.ReadFile(filepath.Join(dir, filepath.Base(sheet)))
+	if err != nil || string(contents) != testSheet {
+		t.Errorf("Unexpected published sheet %q %v", contents, err)
+	}
+}
+
+// The example from the AWS Signature Version 4 documentation
+func TestSign(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	sign(req, hashHex(nil), "us-east-1", "iam", "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", now)
+
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if answer := req.Header.Get("Authorization"); answer != expected {
+		t.Errorf("Expected %s, got %s", expected, answer)
+	}
+}
+
+func TestS3(t *testing.T) {
+	now := time.Date(2024, 6, 8, 12, 0, 0, 0, time.UTC)
+	objects := map[string][]byte{}
+
+	// A stand-in for S3 that checks the signature as S3 would, by signing the
+	// request it received itself
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		if r.Header.Get("X-Amz-Content-Sha256") != hashHex(body) {
+			http.Error(w, "XAmzContentSHA256Mismatch", http.StatusBadRequest)
+			return
+		}
+
+		check, _ := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+		check.Header.Set("Content-Type", r.Header.Get("Content-Type"))
+		check.Header.Set("X-Amz-Content-Sha256", r.Header.Get("X-Amz-Content-Sha256"))
+		sign(check, hashHex(body), "us-east-1", "s3", "AK", "secret", now)
+		if check.Header.Get("Authorization") != r.Header.Get("Authorization") {
+			http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+			return
+		}
+
+		objects[r.URL.EscapedPath()] = body
+	}))
+	defer server.Close()
+
+	sheet := writeSheet(t)
+
+	s := &S3{
+		Endpoint:  server.URL,
+		Region:    "us-east-1",
+		Bucket:    "options",
+		Prefix:    "weekly/",
+		AccessKey: "AK",
+		SecretKey: "secret",
+		Now:       func() time.Time { return now },
+	}
+
+	err := s.Publish(sheet)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if string(objects["/options/weekly/eb_2024-06-07_puts.csv"]) != testSheet {
+		t.Errorf("Unexpected objects %v", objects)
+	}
+
+	s.SecretKey = "wrong"
+	err = s.Publish(sheet)
+	if err == nil || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Errorf("Expected a signature error, got %v", err)
+	}
+}
+
+// smtpSink accepts one connection and returns what it was sent
+func smtpSink(t *testing.T) (string, chan string) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan string, 1)
+	go func() {
+		defer l.Close()
+
+		conn, err := l.Accept()
+		if err != nil {
+			received <- ""
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+
+		reply("220 sink")
+		var data strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				break
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 sink")
+			case command == "DATA":
+				reply("354 go ahead")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				reply("250 ok")
+			case command == "QUIT":
+				reply("221 bye")
+				received <- data.String()
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+		received <- data.String()
+	}()
+
+	return l.Addr().String(), received
+}
+
+// attachment returns the name and decoded contents of the email's attachment
+func attachment(t *testing.T, raw string) (string, []byte) {
+	t.Helper()
+
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Unable to parse email %s", err)
+	}
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("Unable to parse content type %s", err)
+	}
+
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := r.NextPart()
+		if err != nil {
+			t.Fatalf("No attachment found %s", err)
+		}
+		if part.FileName() == "" {
+			continue
+		}
+		encoded, _ := io.ReadAll(part)
+		decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+		if err != nil {
+			t.Fatalf("Unable to decode attachment %s", err)
+		}
+		return part.FileName(), decoded
+	}
+}
+
+func TestEmail(t *testing.T) {
+	sheet := writeSheet(t)
+
+	addr, received := smtpSink(t)
+	err := Email{Server: addr, From: "options@example.com", To: []string{"eb@example.com"}}.Publish(sheet)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	name, contents := attachment(t, <-received)
+	if name != "eb_2024-06-07_puts.csv" || string(contents) != testSheet {
+		t.Errorf("Unexpected attachment %s %q", name, contents)
+	}
+
+	addr, received = smtpSink(t)
+	err = Email{Server: addr, From: "options@example.com", To: []string{"eb@example.com"}, XLSX: true}.Publish(sheet)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	name, contents = attachment(t, <-received)
+	if name != "eb_2024-06-07_puts.xlsx" {
+		t.Errorf("Unexpected attachment name %s", name)
+	}
+	_, err = zip.NewReader(bytes.NewReader(contents), int64(len(contents)))
+	if err != nil {
+		t.Errorf("Attachment is not a workbook %s", err)
+	}
+}
+
+func TestXLSX(t *testing.T) {
+	workbook, err := XLSX([][]string{
+		{"Fill: bid", ""},
+		{"", "=sum(B4:B9999)"},
+		{"  Ticker", "  Strike", "  Lots", "  Collateral", "  Spread %"},
+		{"T & CO", "$  18.50", "  2", "=B4*100*C4", "   12.3%"},
+		{"KO", "$1,065.00", "-", "", ""},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	z, err := zip.NewReader(bytes.NewReader(workbook), int64(len(workbook)))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	parts := map[string]string{}
+	for _, f := range z.File {
+		r, _ := f.Open()
+		b, _ := io.ReadAll(r)
+		parts[f.Name] = string(b)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("Workbook is missing %s", name)
+		}
+	}
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, expected := range []string{
+		`<c r="A1" t="inlineStr"><is><t>Fill: bid</t></is></c>`,
+		`<c r="B2"><f>sum(B4:B9999)</f></c>`,
+		`<c r="A4" t="inlineStr"><is><t>T &amp; CO</t></is></c>`,
+		`<c r="B4" s="1"><v>18.50</v></c>`,
+		`<c r="C4"><v>2</v></c>`,
+		// The strike the collateral formula multiplies is a number
+		`<c r="D4"><f>B4*100*C4</f></c>`,
+		`<c r="E4" s="2"><v>0.123</v></c>`,
+		`<c r="B5" s="1"><v>1065.00</v></c>`,
+		`<c r="C5" t="inlineStr"><is><t>-</t></is></c>`,
+	} {
+		if !strings.Contains(sheet, expected) {
+			t.Errorf("Expected %s in %s", expected, sheet)
+		}
+	}
+
+	if !strings.Contains(parts["xl/styles.xml"], `formatCode="&quot;$&quot;#,##0.00"`) {
+		t.Errorf("Expected a currency format in %s", parts["xl/styles.xml"])
+	}
+}
+
+func TestNumber(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected string
+		style    int
+		ok       bool
+	}{
+		{"18.50", "18.50", 0, true},
+		{"-3", "-3", 0, true},
+		{"$  65.00", "65.00", styleCurrency, true},
+		{"$1,065.00", "1065.00", styleCurrency, true},
+		{"12.3%", "0.123", stylePercent, true},
+		{"  -5.0%", "-0.05", stylePercent, true},
+		{"$", "", 0, false},
+		{"KO", "", 0, false},
+		{"NAN", "", 0, false},
+		{"inf", "", 0, false},
+		{"2024-06-07", "", 0, false},
+	}
+
+	for _, testCase := range testCases {
+		answer, style, ok := number(strings.TrimSpace(testCase.value))
+		if answer != testCase.expected || style != testCase.style || ok != testCase.ok {
+			t.Errorf("ERROR: For %q expected %s %d %v, got %s %d %v", testCase.value, testCase.expected, testCase.style, testCase.ok, answer, style, ok)
+		}
+	}
+}
+
+func TestCellRef(t *testing.T) {
+	testCases := []struct {
+		row, col int
+		expected string
+	}{
+		{0, 0, "A1"},
+		{3, 25, "Z4"},
+		{0, 26, "AA1"},
+		{9, 701, "ZZ10"},
+		{0, 702, "AAA1"},
+	}
+
+	for _, testCase := range testCases {
+		answer := cellRef(testCase.row, testCase.col)
+		if answer != testCase.expected {
+			t.Errorf("ERROR: For %d,%d expected %s, got %s", testCase.row, testCase.col, testCase.expected, answer)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package publish
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3 publishes sheets to a bucket in S3 (or S3-compatible storage, such as
+// MinIO), addressed path-style: Endpoint/Bucket/Prefix+name
+type S3 struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+
+	Client *http.Client     // http.DefaultClient if nil
+	Now    func() time.Time // time.Now if nil
+}
+
+// hmacSHA256 returns the HMAC of data keyed with key
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// hashHex returns the hex SHA256 of data
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// awsEscape percent-encodes everything but the unreserved characters, as
+// SigV4 requires. Slashes are kept if path is set.
+func awsEscape(s string, path bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && path:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// canonicalQuery returns the request's query string in SigV4 canonical form
+func canonicalQuery(req *http.Request) string {
+	query := req.URL.Query()
+
+	keys := []string{}
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := []string{}
+	for _, k := range keys {
+		values := query[k]
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, awsEscape(k, false)+"="+awsEscape(v, false))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to the request,
+// signing the host, content type and any x-amz-* headers. payloadHash is the
+// hex SHA256 of the body.
+func sign(req *http.Request, payloadHash, region, service, accessKey, secretKey string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	day := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.Join(values, ",")
+		}
+	}
+
+	names := []string{}
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + strings.TrimSpace(headers[name]) + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+// Publish uploads the sheet to the bucket
+func (s *S3) Publish(sheet string) error {
+	contents, err := os.ReadFile(sheet)
+	if err != nil {
+		return fmt.Errorf("could not read %s %s", sheet, err)
+	}
+
+	key := awsEscape(s.Prefix+filepath.Base(sheet), true)
+	url := strings.TrimRight(s.Endpoint, "/") + "/" + awsEscape(s.Bucket, false) + "/" + key
+
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(contents))
+	if err != nil {
+		return fmt.Errorf("could not build request for %s %s", url, err)
+	}
+
+	payloadHash := hashHex(contents)
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	sign(req, payloadHash, s.Region, "s3", s.AccessKey, s.SecretKey, now())
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not upload %s to %s %s", sheet, url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("could not upload %s to %s: %s %s", sheet, url, resp.Status, body)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package publish
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// xlsxParts are the fixed parts of a single-sheet workbook
+var xlsxParts = []struct{ name, body string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`},
+	{"xl/styles.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="2"><numFmt numFmtId="164" formatCode="&quot;$&quot;#,##0.00"/><numFmt numFmtId="165" formatCode="0.0%"/></numFmts>
+<fonts count="1"><font/></fonts>
+<fills count="1"><fill/></fills>
+<borders count="1"><border/></borders>
+<cellStyleXfs count="1"><xf/></cellStyleXfs>
+<cellXfs count="3"><xf/><xf numFmtId="164" applyNumberFormat="1"/><xf numFmtId="165" applyNumberFormat="1"/></cellXfs>
+</styleSheet>`},
+}
+
+// Cell styles defined in xl/styles.xml
+const (
+	styleCurrency = 1
+	stylePercent  = 2
+)
+
+// number returns the numeric value of a cell written as a plain number,
+// currency ($  65.00) or percent (  12.3%) and the style to display it with
+func number(value string) (string, int, bool) {
+	style := 0
+	switch {
+	case strings.HasPrefix(value, "$"):
+		value, style = value[1:], styleCurrency
+	case strings.HasSuffix(value, "%"):
+		value, style = value[:len(value)-1], stylePercent
+	}
+	value = strings.ReplaceAll(strings.TrimSpace(value), ",", "")
+
+	f, err := strconv.ParseFloat(value, 64)
+	// Tickers such as NAN and INF are text
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", 0, false
+	}
+	if style == stylePercent {
+		return strconv.FormatFloat(f/100, 'g', 15, 64), style, true
+	}
+	return value, style, true
+}
+
+// cellRef returns the A1-style reference of the cell
+func cellRef(row, col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name + strconv.Itoa(row+1)
+}
+
+// escape returns s escaped for XML text
+func escape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// XLSX returns a minimal single-sheet workbook holding the rows. Numbers,
+// currency and percentages are stored as formatted numbers, cells starting
+// with = as formulas and the rest as text.
+func XLSX(rows [][]string) ([]byte, error) {
+	var sheet strings.Builder
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for r, row := range rows {
+		fmt.Fprintf(&sheet, `<row r="%d">`, r+1)
+		for c, value := range row {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			ref := cellRef(r, c)
+			switch {
+			case strings.HasPrefix(value, "="):
+				fmt.Fprintf(&sheet, `<c r="%s"><f>%s</f></c>`, ref, escape(value[1:]))
+			default:
+				if n, style, ok := number(value); ok && style != 0 {
+					fmt.Fprintf(&sheet, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, n)
+				} else if ok {
+					fmt.Fprintf(&sheet, `<c r="%s"><v>%s</v></c>`, ref, n)
+				} else {
+					fmt.Fprintf(&sheet, `<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, escape(value))
+				}
+			}
+		}
+		sheet.WriteString(`</row>`)
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+
+	parts := append(xlsxParts, struct{ name, body string }{"xl/worksheets/sheet1.xml", sheet.String()})
+	for _, part := range parts {
+		w, err := z.Create(part.name)
+		if err != nil {
+			return nil, fmt.Errorf("could not create %s %s", part.name, err)
+		}
+		_, err = w.Write([]byte(part.body))
+		if err != nil {
+			return nil, fmt.Errorf("could not write %s %s", part.name, err)
+		}
+	}
+
+	err := z.Close()
+	if err != nil {
+		return nil, fmt.Errorf("could not finish workbook %s", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,147 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Exclusion records why a ticker was left out of the run (or a profile)
+type Exclusion struct {
+	Ticker  string `json:"ticker"`
+	Profile string `json:"profile,omitempty"` // empty if excluded from the whole run
+	Reason  string `json:"reason"`
+}
+
+// What became of a sheet
+const (
+	Uploaded    = "uploaded"
+	Placeholder = "placeholder" // there were no rows, so a "no matches" sheet was uploaded
+	Skipped     = "skipped"     // there were no rows, so nothing was uploaded
+	Failed      = "failed"
+)
+
+// Sheet records a sheet a profile produced
+type Sheet struct {
+	Profile   string   `json:"profile"`
+	Name      string   `json:"name"`
+	Rows      int      `json:"rows"`
+	Outcome   string   `json:"outcome"`
+	Published []string `json:"published,omitempty"` // the targets it was published to
+	Error     string   `json:"error,omitempty"`
+}
+
+// Report is a summary of a run
+type Report struct {
+	Started    time.Time   `json:"started"`
+	Finished   time.Time   `json:"finished,omitempty"`
+	Exclusions []Exclusion `json:"exclusions"`
+	Sheets     []Sheet     `json:"sheets"`
+}
+
+var (
+	mu      sync.Mutex
+	current = Report{Started: time.Now()}
+)
+
+// Reset starts a new report
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	current = Report{Started: time.Now()}
+}
+
+// Exclude records that a ticker was excluded, and why
+func Exclude(ticker, profile, reason string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	current.Exclusions = append(current.Exclusions, Exclusion{Ticker: ticker, Profile: profile, Reason: reason})
+}
+
+// AddSheet records what became of a sheet
+func AddSheet(s Sheet) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	current.Sheets = append(current.Sheets, s)
+}
+
+// Current returns a copy of the report so far
+func Current() Report {
+	mu.Lock()
+	defer mu.Unlock()
+
+	r := current
+	r.Exclusions = append([]Exclusion{}, current.Exclusions...)
+	r.Sheets = append([]Sheet{}, current.Sheets...)
+	return r
+}
+
+// PrintSheets prints what became of each sheet
+func PrintSheets() {
+	for _, s := range Current().Sheets {
+		line := fmt.Sprintf("  %-30s %4d rows  %s", s.Name, s.Rows, s.Outcome)
+		if s.Error != "" {
+			line += ": " + s.Error
+		}
+		fmt.Println(line)
+	}
+}
+
+// PrintExclusions prints the tickers excluded for the given profile ("" for
+// the whole run), grouped by reason
+func PrintExclusions(profile string) {
+	byReason := map[string][]string{}
+	for _, e := range Current().Exclusions {
+		if e.Profile != profile {
+			continue
+		}
+		byReason[e.Reason] = append(byReason[e.Reason], e.Ticker)
+	}
+
+	reasons := []string{}
+	for reason := range byReason {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	for _, reason := range reasons {
+		fmt.Printf("  Rejected for %s (%d): %v\n", reason, len(byReason[reason]), byReason[reason])
+	}
+}
+
+// Save marks the report finished and writes it to file as JSON
+func Save(file string) error {
+	mu.Lock()
+	current.Finished = time.Now()
+	mu.Unlock()
+
+	s, err := json.MarshalIndent(Current(), "", " ")
+	if err != nil {
+		return fmt.Errorf("could not marshal run report %s", err)
+	}
+
+	return os.WriteFile(file, s, 0644)
+}
+
+// Load reads a saved report
+func Load(file string) (Report, error) {
+	var r Report
+
+	contents, err := os.ReadFile(file)
+	if err != nil {
+		return r, fmt.Errorf("unable to read run report %s %s", file, err)
+	}
+
+	err = json.Unmarshal(contents, &r)
+	if err != nil {
+		return r, fmt.Errorf("unable to unmarshal run report %s %s", file, err)
+	}
+
+	return r, nil
+}
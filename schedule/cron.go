@@ -0,0 +1,156 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/erikbryant/options/date"
+)
+
+// field is the set of values a cron field matches
+type field struct {
+	values map[int]bool
+	any    bool // the field was "*"
+}
+
+// Spec is a parsed cron expression: minute hour day-of-month month day-of-week.
+// Times are Eastern, like the market.
+type Spec struct {
+	minute, hour, dom, month, dow field
+}
+
+// fieldRanges are the legal values of each cron field, in order
+var fieldRanges = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // 0 and 7 are both Sunday
+}
+
+// parseField parses one cron field: *, a value, a range (a-b), any of those
+// with a step (/n), or a comma separated list of them
+func parseField(s string, min, max int) (field, error) {
+	f := field{values: map[int]bool{}, any: s == "*"}
+
+	for _, part := range strings.Split(s, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return f, fmt.Errorf("bad step in '%s'", part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(bounds[0])
+			hi, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil || lo > hi {
+				return f, fmt.Errorf("bad range '%s'", part)
+			}
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return f, fmt.Errorf("bad value '%s'", part)
+			}
+			lo = n
+			if step == 1 {
+				hi = n
+			}
+		}
+
+		if lo < min || hi > max {
+			return f, fmt.Errorf("'%s' is outside %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			f.values[v] = true
+		}
+	}
+
+	return f, nil
+}
+
+// ParseCron parses a five field cron expression
+func ParseCron(expression string) (Spec, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != len(fieldRanges) {
+		return Spec{}, fmt.Errorf("cron expression '%s' needs %d fields", expression, len(fieldRanges))
+	}
+
+	parsed := make([]field, len(fields))
+	for i, r := range fieldRanges {
+		f, err := parseField(fields[i], r.min, r.max)
+		if err != nil {
+			return Spec{}, fmt.Errorf("cron expression '%s' %s: %s", expression, r.name, err)
+		}
+		parsed[i] = f
+	}
+
+	// Sunday is both 0 and 7
+	if parsed[4].values[7] {
+		parsed[4].values[0] = true
+	}
+
+	return Spec{minute: parsed[0], hour: parsed[1], dom: parsed[2], month: parsed[3], dow: parsed[4]}, nil
+}
+
+// matchesDay returns whether the spec runs on t's day. As in cron, when both
+// the day of month and day of week are restricted, either may match.
+func (s Spec) matchesDay(t time.Time) bool {
+	if !s.month.values[int(t.Month())] {
+		return false
+	}
+
+	dom := s.dom.values[t.Day()]
+	dow := s.dow.values[int(t.Weekday())]
+	switch {
+	case s.dom.any && s.dow.any:
+		return true
+	case s.dom.any:
+		return dow
+	case s.dow.any:
+		return dom
+	}
+	return dom || dow
+}
+
+// Next returns the first time after the given one that the spec matches, or
+// the zero time if it never does (e.g. February 30th)
+func (s Spec) Next(after time.Time) time.Time {
+	loc := date.Eastern()
+	t := after.In(loc).Truncate(time.Minute).Add(time.Minute)
+
+	// Give up after five years; every real expression matches well within that
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		year, month, day := t.Date()
+		if !s.matchesDay(t) {
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.hour.values[t.Hour()] {
+			// Step in absolute time; a wall clock hour may not exist when DST starts
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if !s.minute.values[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,281 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/erikbryant/options/date"
+)
+
+// Tasks a job can run
+const (
+	Precache = "precache" // refresh the candle history, to use up spare quota
+	Scan     = "scan"     // scan, publish the sheets and evaluate alerts
+	Alerts   = "alerts"   // scan and evaluate alerts, without publishing sheets
+)
+
+// Job is a task run on a cron-style schedule
+type Job struct {
+	Name            string `json:"name"`
+	Task            string `json:"task"`
+	Cron            string `json:"cron"`                      // minute hour day-of-month month day-of-week, Eastern
+	TradingDays     bool   `json:"tradingDays,omitempty"`     // only run on days the market is open
+	ExpirationWeeks int    `json:"expirationWeeks,omitempty"` // scan expirations up to this many weeks out (default 1)
+	CacheHours      int    `json:"cacheHours,omitempty"`      // first drop cached web requests older than this (default 72)
+
+	spec Spec
+}
+
+// Jobs is the daemon's configuration
+type Jobs struct {
+	Jobs []Job `json:"jobs"`
+}
+
+// Parse returns the jobs in contents
+func Parse(contents []byte) ([]Job, error) {
+	var jobs Jobs
+
+	err := json.Unmarshal(contents, &jobs)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal schedule %s", err)
+	}
+
+	names := map[string]bool{}
+	for i := range jobs.Jobs {
+		job := &jobs.Jobs[i]
+
+		if job.Name == "" || names[job.Name] {
+			return nil, fmt.Errorf("job %d needs a unique name", i+1)
+		}
+		names[job.Name] = true
+
+		switch job.Task {
+		case Precache, Scan, Alerts:
+		default:
+			return nil, fmt.Errorf("job %s has unknown task '%s'", job.Name, job.Task)
+		}
+
+		job.spec, err = ParseCron(job.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %s", job.Name, err)
+		}
+	}
+
+	return jobs.Jobs, nil
+}
+
+// Load returns the jobs in file
+func Load(file string) ([]Job, error) {
+	contents, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read schedule %s %s", file, err)
+	}
+
+	jobs, err := Parse(contents)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err)
+	}
+
+	return jobs, nil
+}
+
+// Next returns the first time after the given one that the job runs, or the
+// zero time if it never does
+func (job Job) Next(after time.Time) time.Time {
+	t := job.spec.Next(after)
+	for job.TradingDays && !t.IsZero() && !date.IsTradingDay(t) {
+		t = job.spec.Next(t)
+	}
+	return t
+}
+
+// CacheAge returns how old a cached web request the job may use
+func (job Job) CacheAge() time.Duration {
+	if job.CacheHours <= 0 {
+		return 72 * time.Hour
+	}
+	return time.Duration(job.CacheHours) * time.Hour
+}
+
+// Expiration returns the latest expiration a job running at the given time
+// scans: the Friday (or, if it is a holiday, the trading day before it) the
+// given number of weeks out. Once this week's expiration has closed, next
+// week's is the first.
+func Expiration(now time.Time, weeks int) string {
+	if weeks < 1 {
+		weeks = 1
+	}
+
+	today, _ := date.ParseDay(date.Format(now))
+	friday := today.AddDate(0, 0, (int(time.Friday)-int(today.Weekday())+7)%7)
+	if !now.Before(date.CloseTime(date.OnOrBefore(friday))) {
+		friday = friday.AddDate(0, 0, 7)
+	}
+	friday = friday.AddDate(0, 0, 7*(weeks-1))
+
+	return date.Format(date.OnOrBefore(friday))
+}
+
+// JobState is what we know of a job's runs
+type JobState struct {
+	LastRun  time.Time `json:"lastRun,omitempty"` // when its last run was scheduled for
+	Finished time.Time `json:"finished,omitempty"`
+	Error    string    `json:"error,omitempty"` // why the last run failed
+	Next     time.Time `json:"next,omitempty"`  // when it runs next
+}
+
+// State is the daemon's record of its jobs, kept so a restart does not run
+// a job again
+type State struct {
+	Jobs map[string]*JobState `json:"jobs"`
+}
+
+// LoadState reads the state from file. A missing file is a fresh state.
+func LoadState(file string) (*State, error) {
+	s := &State{Jobs: map[string]*JobState{}}
+
+	contents, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read daemon state %s %s", file, err)
+	}
+
+	err = json.Unmarshal(contents, s)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal daemon state %s %s", file, err)
+	}
+	if s.Jobs == nil {
+		s.Jobs = map[string]*JobState{}
+	}
+
+	return s, nil
+}
+
+// Save writes the state to file
+func (s *State) Save(file string) error {
+	b, err := json.MarshalIndent(s, "", " ")
+	if err != nil {
+		return fmt.Errorf("could not marshal daemon state %s", err)
+	}
+
+	return os.WriteFile(file, b, 0644)
+}
+
+// job returns the job's state, adding it if need be
+func (s *State) job(name string) *JobState {
+	js, ok := s.Jobs[name]
+	if !ok {
+		js = &JobState{}
+		s.Jobs[name] = js
+	}
+	return js
+}
+
+// Run is a job's upcoming run
+type Run struct {
+	Job Job
+	At  time.Time
+}
+
+// Upcoming returns each job's next run, soonest first, and records them in
+// the state. Runs missed while the daemon was down are not made up; a job
+// already run at (or after) now is not run again.
+func (s *State) Upcoming(jobs []Job, now time.Time) []Run {
+	runs := []Run{}
+	for _, job := range jobs {
+		js := s.job(job.Name)
+
+		after := now
+		if js.LastRun.After(after) {
+			after = js.LastRun
+		}
+		js.Next = job.Next(after)
+		if js.Next.IsZero() {
+			continue
+		}
+
+		runs = append(runs, Run{Job: job, At: js.Next})
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].At.Before(runs[j].At)
+	})
+
+	return runs
+}
+
+// Started records that the run has begun
+func (s *State) Started(run Run) {
+	js := s.job(run.Job.Name)
+	js.LastRun = run.At
+	js.Finished = time.Time{}
+	js.Error = ""
+}
+
+// Finished records how the run ended
+func (s *State) Finished(run Run, err error, now time.Time) {
+	js := s.job(run.Job.Name)
+	js.Finished = now
+	if err != nil {
+		js.Error = err.Error()
+	}
+}
+
+// attempt runs the task for the run, returning a panic as an error so one
+// bad run does not stop the daemon
+func attempt(run Run, task func(job Job, at time.Time) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return task(run.Job, run.At)
+}
+
+// Daemon runs the jobs as they come due, forever, saving its state to
+// stateFile before and after each run
+func Daemon(jobs []Job, stateFile string, task func(job Job, at time.Time) error) error {
+	s, err := LoadState(stateFile)
+	if err != nil {
+		return err
+	}
+
+	for {
+		runs := s.Upcoming(jobs, time.Now())
+		if len(runs) == 0 {
+			return fmt.Errorf("no jobs will ever run")
+		}
+		err = s.Save(stateFile)
+		if err != nil {
+			return err
+		}
+
+		run := runs[0]
+		fmt.Printf("Next: %s (%s) at %s\n", run.Job.Name, run.Job.Task, run.At.In(date.Eastern()).Format("Mon 2006-01-02 15:04 MST"))
+
+		// Sleep in short steps, so a suspended machine notices when it wakes
+		for wait := time.Until(run.At); wait > 0; wait = time.Until(run.At) {
+			time.Sleep(min(wait, time.Minute))
+		}
+
+		s.Started(run)
+		err = s.Save(stateFile)
+		if err != nil {
+			return err
+		}
+
+		err = attempt(run, task)
+		if err != nil {
+			fmt.Printf("Job %s failed: %s\n", run.Job.Name, err)
+		}
+
+		// Recorded at the next save, before waiting for the next run
+		s.Finished(run, err, time.Now())
+	}
+}
@@ -0,0 +1,199 @@
+package schedule
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/erikbryant/options/date"
+)
+
+// et returns the given Eastern time
+func et(t *testing.T, s string) time.Time {
+	t.Helper()
+	answer, err := time.ParseInLocation("2006-01-02 15:04", s, date.Eastern())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return answer
+}
+
+func TestParseCron(t *testing.T) {
+	errorCases := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+	}
+	for _, errorCase := range errorCases {
+		_, err := ParseCron(errorCase)
+		if err == nil {
+			t.Errorf("ERROR: For '%s' expected an error", errorCase)
+		}
+	}
+}
+
+func TestSpecNext(t *testing.T) {
+	testCases := []struct {
+		cron     string
+		after    string
+		expected string
+	}{
+		// Every weekday evening
+		{"30 17 * * 1-5", "2024-06-07 12:00", "2024-06-07 17:30"},
+		{"30 17 * * 1-5", "2024-06-07 17:30", "2024-06-10 17:30"},
+		// Lists and steps
+		{"0 10,14 * * *", "2024-06-07 10:00", "2024-06-07 14:00"},
+		{"*/15 9 * * *", "2024-06-07 09:31", "2024-06-07 09:45"},
+		// Sunday as 7
+		{"0 8 * * 7", "2024-06-07 12:00", "2024-06-09 08:00"},
+		// Either the day of month or day of week
+		{"0 8 1 * 6", "2024-06-02 12:00", "2024-06-08 08:00"},
+		{"0 8 1 * 6", "2024-06-29 12:00", "2024-07-01 08:00"},
+		// Across the start of daylight saving time
+		{"30 16 * * *", "2024-03-09 17:00", "2024-03-10 16:30"},
+	}
+
+	for _, testCase := range testCases {
+		spec, err := ParseCron(testCase.cron)
+		if err != nil {
+			t.Fatalf("Unexpected error %s", err)
+		}
+		answer := spec.Next(et(t, testCase.after))
+		expected := et(t, testCase.expected)
+		if !answer.Equal(expected) {
+			t.Errorf("ERROR: For '%s' after %s expected %s, got %s", testCase.cron, testCase.after, expected, answer)
+		}
+	}
+
+	spec, _ := ParseCron("0 0 30 2 *")
+	if answer := spec.Next(et(t, "2024-06-07 12:00")); !answer.IsZero() {
+		t.Errorf("ERROR: For February 30th expected no time, got %s", answer)
+	}
+}
+
+func TestJobNext(t *testing.T) {
+	jobs, err := Parse([]byte(`{"jobs": [
+		{"name": "refresh", "task": "precache", "cron": "30 17 * * 1-5", "tradingDays": true},
+		{"name": "any", "task": "precache", "cron": "30 17 * * 1-5"}
+	]}`))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	// Juneteenth is a market holiday
+	answer := jobs[0].Next(et(t, "2024-06-18 18:00"))
+	if expected := et(t, "2024-06-20 17:30"); !answer.Equal(expected) {
+		t.Errorf("ERROR: For %s expected %s, got %s", jobs[0].Name, expected, answer)
+	}
+	answer = jobs[1].Next(et(t, "2024-06-18 18:00"))
+	if expected := et(t, "2024-06-19 17:30"); !answer.Equal(expected) {
+		t.Errorf("ERROR: For %s expected %s, got %s", jobs[1].Name, expected, answer)
+	}
+}
+
+func TestParse(t *testing.T) {
+	errorCases := []string{
+		`not json`,
+		`{"jobs": [{"task": "scan", "cron": "0 17 * * 5"}]}`,
+		`{"jobs": [{"name": "a", "task": "scan", "cron": "0 17 * * 5"}, {"name": "a", "task": "scan", "cron": "0 17 * * 5"}]}`,
+		`{"jobs": [{"name": "a", "task": "sleep", "cron": "0 17 * * 5"}]}`,
+		`{"jobs": [{"name": "a", "task": "scan", "cron": "0 17 * *"}]}`,
+	}
+	for _, errorCase := range errorCases {
+		_, err := Parse([]byte(errorCase))
+		if err == nil {
+			t.Errorf("ERROR: For %s expected an error", errorCase)
+		}
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	testCases := []struct {
+		now      string
+		weeks    int
+		expected string
+	}{
+		{"2024-06-05 12:00", 1, "2024-06-07"},
+		{"2024-06-07 15:59", 1, "2024-06-07"},
+		{"2024-06-07 16:00", 1, "2024-06-14"},
+		{"2024-06-08 09:00", 1, "2024-06-14"},
+		{"2024-06-08 09:00", 2, "2024-06-21"},
+		{"2024-06-09 09:00", 0, "2024-06-14"},
+		// Good Friday; the week's options expire Thursday
+		{"2024-03-25 09:00", 1, "2024-03-28"},
+		{"2024-03-28 16:30", 1, "2024-04-05"},
+	}
+
+	for _, testCase := range testCases {
+		answer := Expiration(et(t, testCase.now), testCase.weeks)
+		if answer != testCase.expected {
+			t.Errorf("ERROR: For %s, %d weeks expected %s, got %s", testCase.now, testCase.weeks, testCase.expected, answer)
+		}
+	}
+}
+
+func TestStateUpcoming(t *testing.T) {
+	jobs, err := Parse([]byte(`{"jobs": [
+		{"name": "sheets", "task": "scan", "cron": "30 16 * * 5"},
+		{"name": "refresh", "task": "precache", "cron": "30 17 * * 1-5", "tradingDays": true}
+	]}`))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "state.json")
+	s, _ := LoadState(file)
+
+	runs := s.Upcoming(jobs, et(t, "2024-06-07 12:00"))
+	if len(runs) != 2 || runs[0].Job.Name != "sheets" || !runs[0].At.Equal(et(t, "2024-06-07 16:30")) {
+		t.Fatalf("Unexpected runs %v", runs)
+	}
+
+	// The daemon restarts moments after starting the run; it is not run again
+	s.Started(runs[0])
+	err = s.Save(file)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	s, err = LoadState(file)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	runs = s.Upcoming(jobs, et(t, "2024-06-07 16:30").Add(-time.Second))
+	if runs[0].Job.Name != "refresh" || !runs[0].At.Equal(et(t, "2024-06-07 17:30")) {
+		t.Errorf("Unexpected first run %v", runs[0])
+	}
+	if !s.Jobs["sheets"].Next.Equal(et(t, "2024-06-14 16:30")) {
+		t.Errorf("Unexpected next sheets run %s", s.Jobs["sheets"].Next)
+	}
+}
+
+func TestAttempt(t *testing.T) {
+	s := &State{Jobs: map[string]*JobState{}}
+	run := Run{Job: Job{Name: "sheets", Task: Scan}, At: et(t, "2024-06-07 16:30")}
+
+	// A task that panics fails the run rather than the daemon
+	s.Started(run)
+	err := attempt(run, func(job Job, at time.Time) error {
+		panic("Unable to get earnings dates")
+	})
+	s.Finished(run, err, et(t, "2024-06-07 16:31"))
+	if s.Jobs["sheets"].Error != "panic: Unable to get earnings dates" {
+		t.Errorf("Unexpected state %+v", s.Jobs["sheets"])
+	}
+
+	err = attempt(run, func(job Job, at time.Time) error {
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Unexpected error %s", err)
+	}
+}
@@ -0,0 +1,56 @@
+package screen
+
+import (
+	"github.com/erikbryant/options/correlation"
+	"github.com/erikbryant/options/security"
+	"github.com/erikbryant/options/sizing"
+)
+
+// Defaults for profiles that do not set their correlation settings
+const (
+	DefaultCorrelation     = 0.7
+	DefaultCorrelationDays = 60
+)
+
+// Skip returns the selections, less any the profile's skip rules exclude
+type Skip func(selections []security.Selection) []security.Selection
+
+// Run selects the profile's puts and calls (kind "put" or "call" for only
+// one of them) expiring through expiration, drops those skip excludes,
+// clusters the underlyings by correlation, keeps the best of each cluster if
+// the profile diversifies and sizes what is left if it has cash. It sets each
+// underlying's Cluster, so callers sharing securities must not run at once.
+func Run(securities []security.Security, expiration string, p security.Params, kind string, skip Skip) (puts, calls []security.Selection, summary sizing.Summary) {
+	puts, calls = []security.Selection{}, []security.Selection{}
+	if kind == "" || kind == "put" {
+		puts = security.SelectPuts(securities, expiration, p)
+	}
+	if kind == "" || kind == "call" {
+		calls = security.SelectCalls(securities, expiration, p)
+	}
+
+	if skip != nil {
+		puts = skip(puts)
+		calls = skip(calls)
+	}
+
+	threshold := p.Correlation
+	if threshold == 0 {
+		threshold = DefaultCorrelation
+	}
+	days := p.CorrelationDays
+	if days == 0 {
+		days = DefaultCorrelationDays
+	}
+	correlation.Cluster(correlation.Underlyings(puts, calls), days, threshold)
+	if p.Diversify {
+		puts = correlation.Diversify(puts)
+		calls = correlation.Diversify(calls)
+	}
+
+	if p.Cash > 0 {
+		summary = sizing.Size(p, puts, calls)
+	}
+
+	return puts, calls, summary
+}
@@ -0,0 +1,61 @@
+package screen
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/erikbryant/options/security"
+)
+
+// candles returns a price history whose daily returns follow f
+func candles(f func(day int) float64) []security.DayRange {
+	result := []security.DayRange{}
+	price := 100.0
+	for day := 0; day <= DefaultCorrelationDays; day++ {
+		result = append(result, security.DayRange{Date: fmt.Sprintf("d%03d", day), Close: price})
+		price *= math.Exp(f(day))
+	}
+	return result
+}
+
+func TestRun(t *testing.T) {
+	up := candles(func(day int) float64 { return 0.01 * math.Sin(float64(day)) })
+	other := candles(func(day int) float64 { return 0.01 * math.Cos(float64(3*day)) })
+
+	securities := []security.Security{
+		{Ticker: "KO", Price: 62, Candles: up, Puts: []security.Contract{{Expiration: "2024-06-14", Strike: 60, Bid: 0.90, Ask: 1.00, Delta: -0.2}}},
+		{Ticker: "PEP", Price: 52, Candles: up, Puts: []security.Contract{{Expiration: "2024-06-14", Strike: 50, Bid: 1.00, Ask: 1.10, Delta: -0.2}}},
+		{Ticker: "T", Price: 18, Candles: other, Puts: []security.Contract{{Expiration: "2024-06-14", Strike: 18, Bid: 0.40, Ask: 0.45, Delta: -0.2}}},
+		{Ticker: "X", Price: 30, Candles: other, Calls: []security.Contract{{Expiration: "2024-06-14", Strike: 30, Bid: 0.60, Ask: 0.70, Delta: 0.2}}},
+	}
+	p := security.Params{Initials: "eb", MaxPrice: 100, MinYield: 1.0, Itm: true}
+
+	puts, calls, _ := Run(securities, "2024-06-14", p, "", nil)
+	if len(puts) != 3 || len(calls) != 1 {
+		t.Fatalf("Expected 3 puts and 1 call, got %v %v", puts, calls)
+	}
+	if securities[0].Cluster != securities[1].Cluster || securities[0].Cluster == securities[2].Cluster {
+		t.Errorf("Expected KO and PEP to share a cluster, got %+v", securities)
+	}
+
+	// Only the best of KO and PEP is kept, and T is skipped
+	skip := func(selections []security.Selection) []security.Selection {
+		result := []security.Selection{}
+		for _, sel := range selections {
+			if sel.Security.Ticker != "T" {
+				result = append(result, sel)
+			}
+		}
+		return result
+	}
+	p.Diversify = true
+	p.Cash = 10000
+	puts, calls, summary := Run(securities, "2024-06-14", p, "put", skip)
+	if len(puts) != 1 || puts[0].Security.Ticker != "PEP" || len(calls) != 0 {
+		t.Fatalf("Expected only the PEP put, got %v %v", puts, calls)
+	}
+	if puts[0].Contract.Lots == 0 || summary.Trades != 1 {
+		t.Errorf("Expected the PEP put to be sized, got %+v %+v", puts[0].Contract, summary)
+	}
+}
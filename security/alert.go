@@ -0,0 +1,67 @@
+package security
+
+import (
+	"fmt"
+	"math"
+)
+
+// AlertRule picks out standout contracts worth telling someone about
+// without waiting for them to open the sheets
+type AlertRule struct {
+	Name             string
+	Type             string  // "put", "call" or "" for both
+	MinAnnualized    float64 // Min gross annualized yield, percent (0 for any)
+	MinNetAnnualized float64 // Min annualized yield net of fees, percent (0 for any)
+	MaxDelta         float64 // Max absolute delta (0 for any)
+	NoEarnings       bool    // Only contracts with no earnings on or before their expiration
+	Webhook          string  // Slack/Discord-compatible webhook URL, or $VARIABLE holding it
+}
+
+// Validate returns an error if the rule could never be delivered or would match everything
+func (rule AlertRule) Validate() error {
+	if rule.Webhook == "" {
+		return fmt.Errorf("alert %s has no webhook", rule.Name)
+	}
+	if rule.Type != "" && rule.Type != "put" && rule.Type != "call" {
+		return fmt.Errorf("alert %s has unknown type '%s'", rule.Name, rule.Type)
+	}
+	if rule.MinAnnualized <= 0 && rule.MinNetAnnualized <= 0 && rule.MaxDelta <= 0 && !rule.NoEarnings {
+		return fmt.Errorf("alert %s has no conditions", rule.Name)
+	}
+	return nil
+}
+
+// Matches returns whether the selected contract meets all of the rule's conditions
+func (rule AlertRule) Matches(s Selection) bool {
+	switch rule.Type {
+	case "put":
+		if s.Call {
+			return false
+		}
+	case "call":
+		if !s.Call {
+			return false
+		}
+	}
+
+	contract := s.Contract
+
+	if contract.Annualized < rule.MinAnnualized {
+		return false
+	}
+
+	if contract.NetAnnualized < rule.MinNetAnnualized {
+		return false
+	}
+
+	if rule.MaxDelta > 0 && math.Abs(contract.Delta) > rule.MaxDelta {
+		return false
+	}
+
+	earnings := s.Security.EarningsDate
+	if rule.NoEarnings && earnings != "" && earnings <= contract.Expiration {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,61 @@
+package security
+
+import (
+	"fmt"
+)
+
+// DefaultLotSize is the number of shares in a standard equity option contract
+const DefaultLotSize = 100
+
+// FeeSchedule is what our broker and the regulators charge us
+type FeeSchedule struct {
+	PerContract float64 // commission per contract
+	PerOrder    float64 // commission per order
+	Regulatory  float64 // regulatory (ORF, OCC, TAF) fees per contract
+	Assignment  float64 // fee per assignment
+}
+
+// Validate returns an error if any fee is negative
+func (fees FeeSchedule) Validate() error {
+	if fees.PerContract < 0 || fees.PerOrder < 0 || fees.Regulatory < 0 || fees.Assignment < 0 {
+		return fmt.Errorf("fees must not be negative %+v", fees)
+	}
+	return nil
+}
+
+// Shares returns the contract's lot size, assuming a standard contract if unknown
+func (contract Contract) Shares() int {
+	if contract.LotSize > 0 {
+		return contract.LotSize
+	}
+	return DefaultLotSize
+}
+
+// Net returns a copy of the (filled) contract with its net-of-fee values
+// computed. Orders are assumed to be for a single contract.
+func (security *Security) Net(contract Contract, fees FeeSchedule, call bool) Contract {
+	lot := float64(contract.Shares())
+
+	contract.NetCredit = contract.Fill*lot - fees.PerContract - fees.Regulatory - fees.PerOrder
+
+	// Puts tie up the strike in cash, calls tie up the shares
+	collateral := contract.Strike * lot
+	if call {
+		collateral = security.Price * lot
+	}
+	if collateral <= 0 {
+		return contract
+	}
+
+	contract.NetYield = 100 * contract.NetCredit / collateral
+	if call {
+		contract.NetIfCalled = 100 * (contract.NetCredit + (contract.Strike-security.Price)*lot - fees.Assignment) / collateral
+	}
+
+	days := float64(max(contract.DaysToExpiration, 1))
+	gross := 100 * contract.Fill * lot / collateral
+	contract.Annualized = gross * 365 / days
+	contract.NetAnnualized = contract.NetYield * 365 / days
+
+	return contract
+}
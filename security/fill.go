@@ -0,0 +1,94 @@
+package security
+
+import (
+	"fmt"
+	"math"
+)
+
+// Fill model kinds
+const (
+	FillBid     = "bid"     // filled at the bid
+	FillMid     = "mid"     // filled halfway between the bid and the ask
+	FillSpread  = "spread"  // filled Fraction of the way from the bid to the ask
+	FillLearned = "learned" // like FillSpread, with Fraction learned from our past fills
+)
+
+// FillModel is the price we expect to be filled at when selling to open
+type FillModel struct {
+	Kind     string  // one of the Fill* kinds; empty means FillBid
+	Fraction float64 // fraction of the bid-ask spread above the bid (FillSpread, FillLearned)
+	Samples  int     // number of past fills Fraction was learned from (FillLearned)
+}
+
+// Price returns the price the model expects the contract to fill at
+func (model FillModel) Price(contract Contract) float64 {
+	if contract.Ask <= contract.Bid {
+		return contract.Bid
+	}
+
+	switch model.Kind {
+	case FillMid:
+		return (contract.Bid + contract.Ask) / 2
+	case FillSpread, FillLearned:
+		fraction := math.Max(0, math.Min(1, model.Fraction))
+		return contract.Bid + fraction*(contract.Ask-contract.Bid)
+	}
+
+	return contract.Bid
+}
+
+// String describes the model, for the sheet header
+func (model FillModel) String() string {
+	switch model.Kind {
+	case FillMid:
+		return "Fill: mid"
+	case FillSpread:
+		return fmt.Sprintf("Fill: bid + %.0f%% of spread", 100*model.Fraction)
+	case FillLearned:
+		return fmt.Sprintf("Fill: bid + %.0f%% of spread (learned from %d fills)", 100*model.Fraction, model.Samples)
+	}
+
+	return "Fill: bid"
+}
+
+// Validate returns an error if the model is not one we know
+func (model FillModel) Validate() error {
+	switch model.Kind {
+	case "", FillBid, FillMid, FillLearned:
+		return nil
+	case FillSpread:
+		if model.Fraction < 0 || model.Fraction > 1 {
+			return fmt.Errorf("fill fraction %f is not between 0 and 1", model.Fraction)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown fill model '%s'", model.Kind)
+}
+
+// Filled returns a copy of the contract with the fill price, and the values
+// derived from it, computed using the given model
+func (security *Security) Filled(contract Contract, model FillModel) Contract {
+	contract.Fill = model.Price(contract)
+
+	contract.PriceBasisDelta = security.Price - (contract.Strike - contract.Fill)
+	contract.BidStrikeRatio = contract.Fill / contract.Strike * 100
+	contract.BidPriceRatio = contract.Fill / security.Price * 100
+	contract.SafetySpread = (security.Price - (contract.Strike - contract.Fill)) / security.Price * 100
+
+	return contract
+}
+
+// fillRef returns what a sheet formula should use for the fill price: the
+// bid or fill cell when there is one, otherwise the value itself
+func fillRef(cols []string, contract Contract) string {
+	if contract.Fill == contract.Bid {
+		if bidCol := colName(cols, "bid"); bidCol[0] != '!' {
+			return fmt.Sprintf("%s%d", bidCol, row)
+		}
+	}
+	if fillCol := colName(cols, "fill"); fillCol[0] != '!' {
+		return fmt.Sprintf("%s%d", fillCol, row)
+	}
+	return fmt.Sprintf("%.2f", contract.Fill)
+}
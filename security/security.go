@@ -2,7 +2,9 @@ package security
 
 import (
 	"fmt"
+	"math"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/erikbryant/options/csv"
@@ -19,15 +21,56 @@ type Contract struct {
 	LastTradeDate time.Time
 	LotSize       int
 	OpenInterest  int64
+	Volume        int64
 	Delta         float64
 	IV            float64
 	// Derived values
-	PriceBasisDelta float64 // Share price minus cost basis
-	LastTradeDays   int64   // Age of last trade in days
-	BidStrikeRatio  float64 // bid / strike
-	BidPriceRatio   float64 // bid / strike
-	SafetySpread    float64 // distance between share price and cost basis
-	CallSpread      float64 // how many strikes out do calls still have bids
+	Fill                    float64 // Price we expect to be filled at
+	PriceBasisDelta         float64 // Share price minus cost basis
+	LastTradeDays           int64   // Age of last trade in calendar days
+	LastTradeTradingDays    int64   // Age of last trade in trading days
+	DaysToExpiration        int64   // Calendar days until expiration
+	TradingDaysToExpiration int64   // Trading sessions left until expiration
+	HoursToExpiration       float64 // Hours until the close on expiration day
+	BidStrikeRatio          float64 // fill / strike
+	BidPriceRatio           float64 // fill / price
+	SafetySpread            float64 // distance between share price and cost basis
+	CallSpread              float64 // how many strikes out do calls still have bids
+	IVHVRatio               float64 // implied volatility / 30 day realized volatility
+	Theo                    float64 // theoretical value, accounting for dividend yield
+	EarlyAssignment         bool    // call likely to be exercised early for the dividend
+	NetCredit               float64 // premium for one contract, less fees
+	NetYield                float64 // net credit / collateral, percent
+	NetIfCalled             float64 // net return if the call is assigned, percent
+	Annualized              float64 // premium / collateral, annualized percent
+	NetAnnualized           float64 // net yield, annualized percent
+	Lots                    int     // suggested number of contracts to trade
+}
+
+// Spread returns the width of the bid-ask spread
+func (contract Contract) Spread() float64 {
+	return contract.Ask - contract.Bid
+}
+
+// SpreadPct returns the width of the bid-ask spread as a percent of the mid
+func (contract Contract) SpreadPct() float64 {
+	mid := (contract.Bid + contract.Ask) / 2
+	if contract.Bid <= 0 || contract.Ask <= 0 || mid <= 0 {
+		return 100
+	}
+	return 100 * contract.Spread() / mid
+}
+
+// LiquidityScore returns a 0-100 estimate of how easily we can get filled.
+// Open interest and volume count up to 1000 and 500 contracts respectively
+// (on a log scale), and the spread counts fully when it is zero and not at
+// all when it is 50% of the mid or wider.
+func (contract Contract) LiquidityScore() float64 {
+	oi := math.Min(1, math.Log10(1+float64(contract.OpenInterest))/math.Log10(1+1000))
+	volume := math.Min(1, math.Log10(1+float64(contract.Volume))/math.Log10(1+500))
+	spread := math.Max(0, 1-contract.SpreadPct()/50)
+
+	return 100 * (0.35*oi + 0.25*volume + 0.4*spread)
 }
 
 // DayRange represents a single (historical) trading day.
@@ -43,13 +86,28 @@ type DayRange struct {
 // Security holds data about a security and its option contracts
 type Security struct {
 	Ticker         string
+	Name           string // company name
+	Industry       string
+	Sector         string
+	Cluster        int // group of tickers whose returns are highly correlated
 	Close          DayRange
-	Price          float64 // latest price
-	PriceChangePct float64 // percent change in price over trailing period
+	Price          float64            // latest price
+	PriceChanges   map[string]float64 // percent change in price, by trailing window
+	Candles        []DayRange         // daily price history, oldest first
 	Puts           []Contract
 	Calls          []Contract
 	EarningsDate   string
 	PE             float64
+	ExDividendDate string  // next ex-dividend date, if known
+	Dividend       float64 // next dividend amount per share
+	DividendYield  float64 // indicated annual dividend yield, percent
+	HV20           float64 // 20 day realized volatility
+	HV30           float64 // 30 day realized volatility
+	HV60           float64 // 60 day realized volatility
+	IV             float64 // at-the-money implied volatility
+	IVHistoryDays  int     // number of days of IV history behind the rank/percentile
+	IVRank         float64 // where IV falls in its 52 week range (0-100)
+	IVPercentile   float64 // percent of the last 52 weeks IV was below today's
 }
 
 // Params holds the parameters for each user's output preferences
@@ -61,16 +119,69 @@ type Params struct {
 	MinSafetySpread float64
 	MinCallSpread   float64
 	MinIfCalled     float64
+	MaxAge          int64   // Max trading days since last trade (0 for any)
+	MaxAgeCalendar  int64   // Max calendar days since last trade (0 for any)
+	MinDTE          int64   // Min calendar days to expiration (0 for any)
+	MaxDTE          int64   // Max calendar days to expiration (0 for any)
+	MinTradingDTE   int64   // Min trading sessions to expiration (0 for any)
+	MaxTradingDTE   int64   // Max trading sessions to expiration (0 for any)
+	MinHours        float64 // Min hours to the close on expiration day (0 for any)
+	MaxHours        float64 // Max hours to the close on expiration day (0 for any)
+	MinIVHVRatio    float64
+	MinIVRank       float64
+	MinOpenInterest int64
+	MinVolume       int64
+	MaxSpread       float64 // Max ask - bid, in dollars (0 for any)
+	MaxSpreadPct    float64 // Max ask - bid, as a percent of the mid (0 for any)
+	MinLiquidity    float64 // Min liquidity score, 0-100
 	Itm             bool
+	Fill            FillModel
+	Fees            FeeSchedule
+	MinNetCredit    float64     // Min premium for one contract, less fees, in dollars
+	Cash            float64     // Cash available to size trades with (0 to not size)
+	MaxTickerPct    float64     // Max percent of cash exposed to one ticker (0 for any)
+	MaxSectorPct    float64     // Max percent of cash exposed to one sector (0 for any)
+	MaxKelly        float64     // Cap on the Kelly fraction of cash for one trade (0 for none)
+	MaxSectorTrades int         // Warn when more suggested trades than this share a sector (0 for any)
+	Diversify       bool        // Only list the best contract from each correlated cluster
+	Correlation     float64     // Min correlation of daily returns to cluster tickers (default 0.7)
+	CorrelationDays int         // Days of returns to correlate (default 60)
+	Folder          string      // Google Drive folder ID to upload to (default: the shared folder)
+	Subfolders      string      // Upload into a subfolder per "expiration" or "week" ("" for none)
+	RetainWeeks     int         // Remove uploaded sheets older than this many weeks (0 to keep them)
+	Placeholders    bool        // Upload a "no matches" sheet rather than skipping empty ones
+	ArchiveFolder   string      // Move old sheets to this Drive folder ID rather than the trash
+	Publish         []string    // Where to publish the sheets: "drive" or targets in publishers.json (default: drive)
+	Alerts          []AlertRule // Standout contracts to post to a webhook after each scan
 	CallCols        []string
 	PutCols         []string
 }
 
+// MinIVHistoryDays is how many days of IV history we need before an IV rank means anything
+const MinIVHistoryDays = 20
+
 // HasOptions returns whether the security has both puts and calls
 func (security *Security) HasOptions() bool {
 	return len(security.Puts) != 0 && len(security.Calls) != 0
 }
 
+// DividendBefore returns whether the stock goes ex-dividend on or before the expiration
+func (security *Security) DividendBefore(expiration string) bool {
+	return security.ExDividendDate != "" && security.ExDividendDate <= expiration
+}
+
+// Yield returns the annual dividend yield as a fraction. If only the next
+// dividend is known, assume it is paid quarterly.
+func (security *Security) Yield() float64 {
+	if security.DividendYield > 0 {
+		return security.DividendYield / 100
+	}
+	if security.Dividend > 0 && security.Price > 0 {
+		return 4 * security.Dividend / security.Price
+	}
+	return 0
+}
+
 // colName returns the column name that a spreadsheet would give it
 func colName(cols []string, col string) string {
 	for i := range cols {
@@ -102,6 +213,15 @@ func (security *Security) CallSpread(expiration string) float64 {
 	return 100.0 * (maxStrike - security.Price) / security.Price
 }
 
+// priceChange returns the formatted price change over the given trailing window
+func (security *Security) priceChange(window string) string {
+	pct, ok := security.PriceChanges[window]
+	if !ok {
+		return fmt.Sprintf("%8s", "")
+	}
+	return fmt.Sprintf("%7.1f%%", pct)
+}
+
 // cellPut returns a header and cell string formatted for printing
 func (security *Security) cellPut(cols []string, col string, contract Contract, expiration string) (string, string) {
 	var h, c string
@@ -119,7 +239,11 @@ func (security *Security) cellPut(cols []string, col string, contract Contract,
 		c = fmt.Sprintf("\"=googlefinance(%s%d, \"\"price\"\")\"", tickerCol, row)
 	case "priceChange":
 		h = fmt.Sprintf("%8s", "1wk Price %")
-		c = fmt.Sprintf("%7.1f%%", security.PriceChangePct)
+		c = security.priceChange("1w")
+	case "priceChange1d", "priceChange1w", "priceChange1m", "priceChangeYTD", "priceChange52wHigh", "priceChange52wLow":
+		window := strings.TrimPrefix(col, "priceChange")
+		h = fmt.Sprintf("%8s", window+" Price %")
+		c = security.priceChange(window)
 	case "strike":
 		h = fmt.Sprintf("%8s", "Strike")
 		c = fmt.Sprintf("$%7.02f", contract.Strike)
@@ -132,50 +256,111 @@ func (security *Security) cellPut(cols []string, col string, contract Contract,
 	case "ask":
 		h = fmt.Sprintf("%8s", "Ask")
 		c = fmt.Sprintf("$%7.02f", contract.Ask)
+	case "fill":
+		h = fmt.Sprintf("%8s", "Fill")
+		c = fmt.Sprintf("$%7.02f", contract.Fill)
 	case "bidStrikeRatio":
 		h = fmt.Sprintf("%8s", "B/S ratio")
-		bidCol := colName(cols, "bid")
+		fill := fillRef(cols, contract)
 		strikeCol := colName(cols, "strike")
-		c = fmt.Sprintf("=%s%d/%s%d", bidCol, row, strikeCol, row)
+		c = fmt.Sprintf("=%s/%s%d", fill, strikeCol, row)
 		// c = fmt.Sprintf("%8.1f%%", contract.BidStrikeRatio)
 	case "bidPriceRatio":
 		h = fmt.Sprintf("%8s", "B/P ratio")
-		bidCol := colName(cols, "bid")
+		fill := fillRef(cols, contract)
 		priceCol := colName(cols, "price")
-		c = fmt.Sprintf("=%s%d/%s%d", bidCol, row, priceCol, row)
+		c = fmt.Sprintf("=%s/%s%d", fill, priceCol, row)
 		// c = fmt.Sprintf("%8.1f%%", contract.BidPriceRatio)
 	case "ifCalled":
 		h = fmt.Sprintf("%8s", "If Called")
-		bidCol := colName(cols, "bid")
+		fill := fillRef(cols, contract)
 		priceCol := colName(cols, "price")
 		strikeCol := colName(cols, "strike")
-		c = fmt.Sprintf("=(%s%d+%s%d-%s%d)/%s%d", bidCol, row, strikeCol, row, priceCol, row, priceCol, row)
+		c = fmt.Sprintf("=(%s+%s%d-%s%d)/%s%d", fill, strikeCol, row, priceCol, row, priceCol, row)
+	case "openInterest":
+		h = fmt.Sprintf("%8s", "Open Int")
+		c = fmt.Sprintf("%8d", contract.OpenInterest)
+	case "volume":
+		h = fmt.Sprintf("%8s", "Volume")
+		c = fmt.Sprintf("%8d", contract.Volume)
+	case "spread":
+		h = fmt.Sprintf("%8s", "Spread")
+		c = fmt.Sprintf("$%7.02f", contract.Spread())
+	case "spreadPct":
+		h = fmt.Sprintf("%8s", "Spread %")
+		c = fmt.Sprintf("%7.1f%%", contract.SpreadPct())
+	case "liquidity":
+		h = fmt.Sprintf("%8s", "Liquidity")
+		c = fmt.Sprintf("%8.0f", contract.LiquidityScore())
 	case "delta":
 		h = fmt.Sprintf("%8s", "Delta")
 		c = fmt.Sprintf("%7.1f", contract.Delta)
 	case "IV":
 		h = fmt.Sprintf("%8s", "IV")
 		c = fmt.Sprintf("%7.1f", contract.IV)
+	case "hv20":
+		h = fmt.Sprintf("%8s", "HV20")
+		c = fmt.Sprintf("%7.2f", security.HV20)
+	case "hv30":
+		h = fmt.Sprintf("%8s", "HV30")
+		c = fmt.Sprintf("%7.2f", security.HV30)
+	case "hv60":
+		h = fmt.Sprintf("%8s", "HV60")
+		c = fmt.Sprintf("%7.2f", security.HV60)
+	case "ivHvRatio":
+		h = fmt.Sprintf("%8s", "IV/HV")
+		c = fmt.Sprintf("%7.2f", contract.IVHVRatio)
+	case "ivRank":
+		h = fmt.Sprintf("%8s", "IV Rank")
+		c = fmt.Sprintf("%8s", "")
+		if security.IVHistoryDays >= MinIVHistoryDays {
+			c = fmt.Sprintf("%7.0f", security.IVRank)
+		}
+	case "ivPercentile":
+		h = fmt.Sprintf("%8s", "IV Pctl")
+		c = fmt.Sprintf("%8s", "")
+		if security.IVHistoryDays >= MinIVHistoryDays {
+			c = fmt.Sprintf("%7.0f", security.IVPercentile)
+		}
 	case "safetySpread":
 		h = fmt.Sprintf("%8s", "Safety")
 		priceCol := colName(cols, "price")
-		bidCol := colName(cols, "bid")
+		fill := fillRef(cols, contract)
 		strikeCol := colName(cols, "strike")
-		c = fmt.Sprintf("=(%s%d-(%s%d-%s%d))/%s%d", priceCol, row, strikeCol, row, bidCol, row, priceCol, row)
+		c = fmt.Sprintf("=(%s%d-(%s%d-%s))/%s%d", priceCol, row, strikeCol, row, fill, priceCol, row)
 		// c = fmt.Sprintf("%7.1f%%", contract.SafetySpread)
 	case "callSpread":
 		h = fmt.Sprintf("%8s", "CallSprd")
 		c = fmt.Sprintf("%7.1f%%", contract.CallSpread)
 	case "age":
+		// Trading days, so weekends and holidays do not count against a contract
 		h = fmt.Sprintf("%8s", "Age")
-		// TODO: If it is the weekend, then make the threshold for
-		// printing higher (i.e., count these as business days, not
-		// calendar days)
 		var lastTrade string
-		if contract.LastTradeDays >= 2 {
-			lastTrade = fmt.Sprintf("%dd", contract.LastTradeDays)
+		if contract.LastTradeTradingDays >= 1 {
+			lastTrade = fmt.Sprintf("%dd", contract.LastTradeTradingDays)
 		}
 		c = fmt.Sprintf("%8s", lastTrade)
+	case "ageCalendar":
+		h = fmt.Sprintf("%8s", "Cal Age")
+		c = fmt.Sprintf("%7dd", contract.LastTradeDays)
+	case "dte":
+		h = fmt.Sprintf("%8s", "DTE")
+		c = fmt.Sprintf("%8d", contract.DaysToExpiration)
+	case "tradingDte":
+		h = fmt.Sprintf("%8s", "Trade DTE")
+		c = fmt.Sprintf("%8d", contract.TradingDaysToExpiration)
+	case "hoursToExpiration":
+		h = fmt.Sprintf("%8s", "Hours Left")
+		c = fmt.Sprintf("%8.1f", contract.HoursToExpiration)
+	case "cluster":
+		h = fmt.Sprintf("%8s", "Cluster")
+		c = fmt.Sprintf("%8d", security.Cluster)
+	case "sector":
+		h = fmt.Sprintf("%8s", "Sector")
+		c = fmt.Sprintf("\"%s\"", security.Sector)
+	case "industry":
+		h = fmt.Sprintf("%8s", "Industry")
+		c = fmt.Sprintf("\"%s\"", security.Industry)
 	case "earnings":
 		h = fmt.Sprintf("%8s", "Earnings")
 		earnings := ""
@@ -183,6 +368,22 @@ func (security *Security) cellPut(cols []string, col string, contract Contract,
 			earnings = "E"
 		}
 		c = fmt.Sprintf("%8s", earnings)
+	case "exDividend":
+		h = fmt.Sprintf("%10s", "Ex-Div")
+		c = fmt.Sprintf("%10s", security.ExDividendDate)
+	case "dividend":
+		h = fmt.Sprintf("%8s", "Dividend")
+		c = ""
+		if security.Dividend > 0 {
+			c = fmt.Sprintf("$%7.03f", security.Dividend)
+		}
+	case "theo":
+		h = fmt.Sprintf("%8s", "Theo")
+		c = fmt.Sprintf("$%7.02f", contract.Theo)
+	case "theoEdge":
+		// How much richer the bid is than the theoretical value
+		h = fmt.Sprintf("%8s", "Bid-Theo")
+		c = fmt.Sprintf("$%7.02f", contract.Bid-contract.Theo)
 	case "pe":
 		h = fmt.Sprintf("%8s", "P/E Ratio")
 		if security.PE == 0 {
@@ -212,7 +413,7 @@ func (security *Security) cellPut(cols []string, col string, contract Contract,
 		c = fmt.Sprintf("\"=if(%s%d = 0, 0, (1+%s%d) - (1-(1+%s%d))/((1+%s%d)/(1-(1+%s%d))))\"", deltaCol, row, deltaCol, row, deltaCol, row, deltaCol, row, deltaCol, row)
 	case "lots":
 		h = fmt.Sprintf("%8s", "Lots")
-		c = fmt.Sprintf("%8d", 0)
+		c = fmt.Sprintf("%8d", contract.Lots)
 	case "exposure":
 		h = fmt.Sprintf("%8s", "Exposure")
 		strikeCol := colName(cols, "strike")
@@ -227,13 +428,35 @@ func (security *Security) cellPut(cols []string, col string, contract Contract,
 		c = fmt.Sprintf("=%s%d*%s%d*%s%d", priceCol, row, lotSizeCol, row, lotsCol, row)
 	case "premium":
 		h = fmt.Sprintf("%8s", "Premium")
-		bidCol := colName(cols, "bid")
+		fill := fillRef(cols, contract)
 		lotSizeCol := colName(cols, "lotSize")
 		lotsCol := colName(cols, "lots")
-		c = fmt.Sprintf("=%s%d*%s%d*%s%d", bidCol, row, lotSizeCol, row, lotsCol, row)
+		c = fmt.Sprintf("=%s*%s%d*%s%d", fill, lotSizeCol, row, lotsCol, row)
+	case "netCredit":
+		h = fmt.Sprintf("%8s", "Net Credit")
+		c = fmt.Sprintf("$%7.02f", contract.NetCredit)
+	case "netPremium":
+		h = fmt.Sprintf("%8s", "Net Premium")
+		lotsCol := colName(cols, "lots")
+		c = fmt.Sprintf("=%.2f*%s%d", contract.NetCredit, lotsCol, row)
+	case "netYield":
+		h = fmt.Sprintf("%8s", "Net Yield")
+		c = fmt.Sprintf("%7.2f%%", contract.NetYield)
+	case "netIfCalled":
+		h = fmt.Sprintf("%8s", "Net If Called")
+		c = fmt.Sprintf("%7.2f%%", contract.NetIfCalled)
+	case "annualized":
+		h = fmt.Sprintf("%8s", "Annualized")
+		c = fmt.Sprintf("%7.1f%%", contract.Annualized)
+	case "netAnnualized":
+		h = fmt.Sprintf("%8s", "Net Annualized")
+		c = fmt.Sprintf("%7.1f%%", contract.NetAnnualized)
 	case "notes":
 		h = fmt.Sprintf("%8s", "Notes")
 		c = fmt.Sprintf("%8s", "")
+	case "held":
+		h = fmt.Sprintf("%8s", "Held")
+		c = fmt.Sprintf("%8s", "")
 	case "otmItm":
 		h = fmt.Sprintf("%8s", "OTM/ITM")
 		if contract.Strike >= security.Price {
@@ -271,6 +494,29 @@ func (security *Security) cellCall(cols []string, col string, contract Contract,
 		deltaCol := colName(cols, "delta")
 		c = fmt.Sprintf("\"=if(%s%d = 0, 0, abs(%s%d) - (1-abs(%s%d))/(abs(%s%d)/(1-abs(%s%d))))\"", deltaCol, row, deltaCol, row, deltaCol, row, deltaCol, row, deltaCol, row)
 
+	case "earlyAssignment":
+		// An ITM call whose extrinsic value is less than the upcoming
+		// dividend is likely to be exercised the day before ex-dividend.
+		h = fmt.Sprintf("%8s", "Early Asgn")
+		risk := ""
+		if contract.EarlyAssignment {
+			risk = "RISK"
+		}
+		c = fmt.Sprintf("%8s", risk)
+
+	case "ifCalledDiv":
+		// If called at expiration, we also collect any dividend before then.
+		// If called early, we do not.
+		h = fmt.Sprintf("%8s", "If Called+Div")
+		dividend := 0.0
+		if security.DividendBefore(contract.Expiration) && !contract.EarlyAssignment {
+			dividend = security.Dividend
+		}
+		fill := fillRef(cols, contract)
+		priceCol := colName(cols, "price")
+		strikeCol := colName(cols, "strike")
+		c = fmt.Sprintf("=(%s+%s%d+%.3f-%s%d)/%s%d", fill, strikeCol, row, dividend, priceCol, row, priceCol, row)
+
 	default:
 		// Everything else is the same for a put as for a call
 		h, c = security.cellPut(cols, col, contract, expiration)
@@ -280,14 +526,15 @@ func (security *Security) cellCall(cols []string, col string, contract Contract,
 }
 
 // formatHeader formats the header for the table
-func (security *Security) formatHeader(cols []string) string {
+func (security *Security) formatHeader(cols []string, fill FillModel) string {
 	output := ""
 	separator := ","
 
-	// The row with space for the available cash and the week's yield percent
-	for _, col := range cols {
-		switch col {
-		case "premium":
+	// The row with space for the available cash and the week's yield percent.
+	// It also notes the fill price assumption the sheet was built with.
+	for i, col := range cols {
+		switch {
+		case col == "premium":
 			pname := colName(cols, "premium")
 			ename := colName(cols, "exposure")
 			if ename[0] == '!' {
@@ -295,6 +542,8 @@ func (security *Security) formatHeader(cols []string) string {
 				ename = colName(cols, "outlay")
 			}
 			output += fmt.Sprintf("=%s2/%s2", pname, ename)
+		case i == 0:
+			output += fill.String()
 		}
 		output += separator
 	}
@@ -329,7 +578,7 @@ func (security *Security) formatHeader(cols []string) string {
 }
 
 // formatPut formats the put data for a single ticker
-func (security *Security) formatPut(p Params, put int, csv bool, expiration string) string {
+func (security *Security) formatPut(p Params, put Contract, csv bool, expiration string) string {
 	var separator string
 	var output string
 
@@ -340,7 +589,7 @@ func (security *Security) formatPut(p Params, put int, csv bool, expiration stri
 	}
 
 	for _, col := range p.PutCols {
-		_, c := security.cellPut(p.PutCols, col, security.Puts[put], expiration)
+		_, c := security.cellPut(p.PutCols, col, put, expiration)
 		output += c
 		output += separator
 	}
@@ -350,7 +599,7 @@ func (security *Security) formatPut(p Params, put int, csv bool, expiration stri
 }
 
 // formatCall formats the call data for a single ticker
-func (security *Security) formatCall(p Params, call int, csv bool, expiration string) string {
+func (security *Security) formatCall(p Params, call Contract, csv bool, expiration string) string {
 	var separator string
 	var output string
 
@@ -361,7 +610,7 @@ func (security *Security) formatCall(p Params, call int, csv bool, expiration st
 	}
 
 	for _, col := range p.CallCols {
-		_, c := security.cellCall(p.CallCols, col, security.Calls[call], expiration)
+		_, c := security.cellCall(p.CallCols, col, call, expiration)
 		output += c
 		output += separator
 	}
@@ -370,44 +619,56 @@ func (security *Security) formatCall(p Params, call int, csv bool, expiration st
 	return output
 }
 
-var row = 1
-
-// printPut prints the put data for a single ticker to the personalized CSV files
-func (security *Security) printPut(p Params, put int, header bool, expiration string, file string) {
-	var output string
+// HeaderRows is the number of rows formatHeader writes above the data
+const HeaderRows = 3
 
-	if header {
-		row = 1
+// row is the sheet row being formatted, for the cell formulas. rowMu
+// serializes the formatting of whole sheets.
+var (
+	row   = 1
+	rowMu sync.Mutex
+)
 
-		output = security.formatHeader(p.PutCols)
-		csv.AppendFile(file, output, true)
+// FormatPuts returns the puts sheet, header and all, as CSV. It is empty if
+// there are no puts.
+func FormatPuts(puts []Selection, expiration string, p Params) string {
+	rowMu.Lock()
+	defer rowMu.Unlock()
 
-		row += strings.Count(output, "\n")
+	output := ""
+	for i, put := range puts {
+		if i == 0 {
+			row = 1
+			header := put.Security.formatHeader(p.PutCols, p.Fill)
+			output += header
+			row += strings.Count(header, "\n")
+		}
+		line := put.Security.formatPut(p, put.Contract, true, expiration)
+		output += line
+		row += strings.Count(line, "\n")
 	}
-
-	output = security.formatPut(p, put, true, expiration)
-	csv.AppendFile(file, output, false)
-
-	row += strings.Count(output, "\n")
+	return output
 }
 
-// printCall prints the call data for a single ticker to the personalized CSV files
-func (security *Security) printCall(p Params, call int, header bool, expiration string, file string) {
-	var output string
+// FormatCalls returns the calls sheet, header and all, as CSV. It is empty
+// if there are no calls.
+func FormatCalls(calls []Selection, expiration string, p Params) string {
+	rowMu.Lock()
+	defer rowMu.Unlock()
 
-	if header {
-		row = 1
-
-		output = security.formatHeader(p.CallCols)
-		csv.AppendFile(file, output, true)
-
-		row += strings.Count(output, "\n")
+	output := ""
+	for i, call := range calls {
+		if i == 0 {
+			row = 1
+			header := call.Security.formatHeader(p.CallCols, p.Fill)
+			output += header
+			row += strings.Count(header, "\n")
+		}
+		line := call.Security.formatCall(p, call.Contract, true, expiration)
+		output += line
+		row += strings.Count(line, "\n")
 	}
-
-	output = security.formatCall(p, call, true, expiration)
-	csv.AppendFile(file, output, false)
-
-	row += strings.Count(output, "\n")
+	return output
 }
 
 func useThisContract(contract Contract, expiration string, p Params) bool {
@@ -435,10 +696,86 @@ func useThisContract(contract Contract, expiration string, p Params) bool {
 		return false
 	}
 
+	if p.MaxAge > 0 && contract.LastTradeTradingDays > p.MaxAge {
+		return false
+	}
+
+	if p.MaxAgeCalendar > 0 && contract.LastTradeDays > p.MaxAgeCalendar {
+		return false
+	}
+
+	if p.MinDTE > 0 && contract.DaysToExpiration < p.MinDTE {
+		return false
+	}
+
+	if p.MaxDTE > 0 && contract.DaysToExpiration > p.MaxDTE {
+		return false
+	}
+
+	if p.MinTradingDTE > 0 && contract.TradingDaysToExpiration < p.MinTradingDTE {
+		return false
+	}
+
+	if p.MaxTradingDTE > 0 && contract.TradingDaysToExpiration > p.MaxTradingDTE {
+		return false
+	}
+
+	if p.MinHours > 0 && contract.HoursToExpiration < p.MinHours {
+		return false
+	}
+
+	if p.MaxHours > 0 && contract.HoursToExpiration > p.MaxHours {
+		return false
+	}
+
+	if contract.IVHVRatio < p.MinIVHVRatio {
+		return false
+	}
+
+	if contract.OpenInterest < p.MinOpenInterest {
+		return false
+	}
+
+	if contract.Volume < p.MinVolume {
+		return false
+	}
+
+	if p.MaxSpread > 0 && contract.Spread() > p.MaxSpread {
+		return false
+	}
+
+	if p.MaxSpreadPct > 0 && contract.SpreadPct() > p.MaxSpreadPct {
+		return false
+	}
+
+	if contract.LiquidityScore() < p.MinLiquidity {
+		return false
+	}
+
+	if contract.NetCredit < p.MinNetCredit {
+		return false
+	}
+
+	return true
+}
+
+// useThisSecurity returns whether the security itself passes the filters
+func useThisSecurity(security Security, p Params) bool {
+	if p.MinIVRank > 0 {
+		// Without enough history we cannot tell whether IV is rich
+		if security.IVHistoryDays < MinIVHistoryDays || security.IVRank < p.MinIVRank {
+			return false
+		}
+	}
+
 	return true
 }
 
 func useThisPut(security Security, contract Contract, expiration string, p Params) bool {
+	if !useThisSecurity(security, p) {
+		return false
+	}
+
 	if !useThisContract(contract, expiration, p) {
 		return false
 	}
@@ -461,6 +798,10 @@ func useThisPut(security Security, contract Contract, expiration string, p Param
 }
 
 func useThisCall(security Security, contract Contract, expiration string, p Params) bool {
+	if !useThisSecurity(security, p) {
+		return false
+	}
+
 	if !useThisContract(contract, expiration, p) {
 		return false
 	}
@@ -469,7 +810,7 @@ func useThisCall(security Security, contract Contract, expiration string, p Para
 		return false
 	}
 
-	ifCalled := (contract.Bid + contract.Strike - security.Price) / security.Price
+	ifCalled := (contract.Fill + contract.Strike - security.Price) / security.Price
 	if ifCalled < p.MinIfCalled {
 		return false
 	}
@@ -482,31 +823,73 @@ func useThisCall(security Security, contract Contract, expiration string, p Para
 	return true
 }
 
-// Print writes a filtered set of options to CSV files
-func Print(securities []Security, expiration string, p Params) (string, string) {
-	putsSheet := p.Initials + "_" + expiration + "_puts.csv"
-	header := true
-	for _, security := range securities {
-		for put, contract := range security.Puts {
-			if !useThisPut(security, contract, expiration, p) {
+// Selection is a contract that passed a profile's filters
+type Selection struct {
+	Security *Security
+	Contract Contract // filled, net of fees and sized for the profile
+	Call     bool
+}
+
+// SelectPuts returns the puts that pass the profile's filters
+func SelectPuts(securities []Security, expiration string, p Params) []Selection {
+	selections := []Selection{}
+	for i := range securities {
+		security := &securities[i]
+		for _, contract := range security.Puts {
+			contract = security.Net(security.Filled(contract, p.Fill), p.Fees, false)
+			if !useThisPut(*security, contract, expiration, p) {
 				continue
 			}
-			security.printPut(p, put, header, expiration, putsSheet)
-			header = false
+			selections = append(selections, Selection{Security: security, Contract: contract})
 		}
 	}
+	return selections
+}
 
-	callsSheet := p.Initials + "_" + expiration + "_calls.csv"
-	header = true
-	for _, security := range securities {
-		for call, contract := range security.Calls {
-			if !useThisCall(security, contract, expiration, p) {
+// SelectCalls returns the calls that pass the profile's filters
+func SelectCalls(securities []Security, expiration string, p Params) []Selection {
+	selections := []Selection{}
+	for i := range securities {
+		security := &securities[i]
+		for _, contract := range security.Calls {
+			contract = security.Net(security.Filled(contract, p.Fill), p.Fees, true)
+			if !useThisCall(*security, contract, expiration, p) {
 				continue
 			}
-			security.printCall(p, call, header, expiration, callsSheet)
-			header = false
+			selections = append(selections, Selection{Security: security, Contract: contract, Call: true})
 		}
 	}
+	return selections
+}
+
+// Sheet is a CSV file Print wrote (or would have, had there been any rows)
+type Sheet struct {
+	Name string
+	Rows int // data rows, not counting the header
+}
+
+// Print writes the selected puts and calls to CSV files. A sheet with no
+// rows is not written.
+func Print(puts, calls []Selection, expiration string, p Params) (Sheet, Sheet) {
+	putsSheet := Sheet{Name: p.Initials + "_" + expiration + "_puts.csv", Rows: len(puts)}
+	if putsSheet.Rows > 0 {
+		csv.AppendFile(putsSheet.Name, FormatPuts(puts, expiration, p), true)
+	}
+
+	callsSheet := Sheet{Name: p.Initials + "_" + expiration + "_calls.csv", Rows: len(calls)}
+	if callsSheet.Rows > 0 {
+		csv.AppendFile(callsSheet.Name, FormatCalls(calls, expiration, p), true)
+	}
 
 	return putsSheet, callsSheet
 }
+
+// NoMatches returns the contents of a sheet saying that nothing matched the profile
+func NoMatches(expiration string, p Params) string {
+	return fmt.Sprintf("No contracts matched profile %s for expirations through %s\n", p.Initials, expiration)
+}
+
+// Placeholder writes a sheet saying that nothing matched the profile
+func Placeholder(sheet Sheet, expiration string, p Params) error {
+	return csv.AppendFile(sheet.Name, NoMatches(expiration, p), true)
+}
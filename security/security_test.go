@@ -1,6 +1,9 @@
 package security
 
 import (
+	"math"
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -26,3 +29,266 @@ func TestHasOptions(t *testing.T) {
 		t.Errorf("ERROR: For %v expected %v, got %v", security, true, answer)
 	}
 }
+
+func TestSpreadPct(t *testing.T) {
+	testCases := []struct {
+		bid      float64
+		ask      float64
+		expected float64
+	}{
+		{1.00, 1.00, 0},
+		{0.90, 1.10, 20},
+		{0.00, 0.10, 100},
+		{0.10, 0.00, 100},
+	}
+
+	for _, testCase := range testCases {
+		contract := Contract{Bid: testCase.bid, Ask: testCase.ask}
+		answer := contract.SpreadPct()
+		if math.Abs(answer-testCase.expected) > 0.000001 {
+			t.Errorf("For %f/%f expected %f, got %f", testCase.bid, testCase.ask, testCase.expected, answer)
+		}
+	}
+}
+
+func TestLiquidityScore(t *testing.T) {
+	testCases := []struct {
+		contract Contract
+		expected float64
+	}{
+		{Contract{Bid: 1, Ask: 1, OpenInterest: 1000, Volume: 500}, 100},
+		{Contract{Bid: 1, Ask: 1, OpenInterest: 5000, Volume: 5000}, 100},
+		{Contract{Bid: 0, Ask: 1, OpenInterest: 0, Volume: 0}, 0},
+		{Contract{Bid: 0.9, Ask: 1.1, OpenInterest: 0, Volume: 0}, 40 * (1 - 20.0/50)},
+	}
+
+	for _, testCase := range testCases {
+		answer := testCase.contract.LiquidityScore()
+		if math.Abs(answer-testCase.expected) > 0.000001 {
+			t.Errorf("For %v expected %f, got %f", testCase.contract, testCase.expected, answer)
+		}
+	}
+}
+
+func TestUseThisContractLiquidity(t *testing.T) {
+	contract := Contract{
+		Expiration:   "2024-09-13",
+		Strike:       10,
+		Bid:          0.90,
+		Ask:          1.10,
+		OpenInterest: 100,
+		Volume:       10,
+	}
+
+	testCases := []struct {
+		p        Params
+		expected bool
+	}{
+		{Params{MaxPrice: 100}, true},
+		{Params{MaxPrice: 100, MinOpenInterest: 100}, true},
+		{Params{MaxPrice: 100, MinOpenInterest: 101}, false},
+		{Params{MaxPrice: 100, MinVolume: 11}, false},
+		{Params{MaxPrice: 100, MaxSpread: 0.25}, true},
+		{Params{MaxPrice: 100, MaxSpread: 0.15}, false},
+		{Params{MaxPrice: 100, MaxSpreadPct: 15}, false},
+		{Params{MaxPrice: 100, MinLiquidity: 90}, false},
+	}
+
+	for _, testCase := range testCases {
+		answer := useThisContract(contract, "2024-09-13", testCase.p)
+		if answer != testCase.expected {
+			t.Errorf("For %v expected %v, got %v", testCase.p, testCase.expected, answer)
+		}
+	}
+}
+
+func TestUseThisContractTimes(t *testing.T) {
+	// Last traded Friday, expiring next Friday
+	contract := Contract{
+		Expiration:              "2024-09-13",
+		Strike:                  10,
+		Bid:                     1,
+		LastTradeDays:           3,
+		LastTradeTradingDays:    1,
+		DaysToExpiration:        7,
+		TradingDaysToExpiration: 5,
+		HoursToExpiration:       150.5,
+	}
+
+	testCases := []struct {
+		p        Params
+		expected bool
+	}{
+		{Params{MaxPrice: 100}, true},
+		{Params{MaxPrice: 100, MaxAge: 1}, true},
+		{Params{MaxPrice: 100, MaxAgeCalendar: 2}, false},
+		{Params{MaxPrice: 100, MaxAgeCalendar: 3}, true},
+		{Params{MaxPrice: 100, MinDTE: 8}, false},
+		{Params{MaxPrice: 100, MaxDTE: 6}, false},
+		{Params{MaxPrice: 100, MinTradingDTE: 5, MaxTradingDTE: 5}, true},
+		{Params{MaxPrice: 100, MinTradingDTE: 6}, false},
+		{Params{MaxPrice: 100, MaxTradingDTE: 4}, false},
+		{Params{MaxPrice: 100, MinHours: 150}, true},
+		{Params{MaxPrice: 100, MinHours: 151}, false},
+		{Params{MaxPrice: 100, MaxHours: 150}, false},
+	}
+
+	for _, testCase := range testCases {
+		answer := useThisContract(contract, "2024-09-13", testCase.p)
+		if answer != testCase.expected {
+			t.Errorf("For %+v expected %v, got %v", testCase.p, testCase.expected, answer)
+		}
+	}
+}
+
+func TestFillModelPrice(t *testing.T) {
+	contract := Contract{Bid: 1.00, Ask: 1.20}
+
+	testCases := []struct {
+		model    FillModel
+		expected float64
+	}{
+		{FillModel{}, 1.00},
+		{FillModel{Kind: FillBid}, 1.00},
+		{FillModel{Kind: FillMid}, 1.10},
+		{FillModel{Kind: FillSpread, Fraction: 0.25}, 1.05},
+		{FillModel{Kind: FillLearned, Fraction: 0.75}, 1.15},
+		{FillModel{Kind: FillSpread, Fraction: 2}, 1.20},
+	}
+
+	for _, testCase := range testCases {
+		answer := testCase.model.Price(contract)
+		if math.Abs(answer-testCase.expected) > 0.000001 {
+			t.Errorf("For %v expected %f, got %f", testCase.model, testCase.expected, answer)
+		}
+	}
+
+	// Crossed or locked markets fill at the bid
+	answer := FillModel{Kind: FillMid}.Price(Contract{Bid: 1.00, Ask: 0.90})
+	if answer != 1.00 {
+		t.Errorf("Expected 1.00, got %f", answer)
+	}
+}
+
+func TestFilled(t *testing.T) {
+	security := Security{Price: 10}
+	contract := Contract{Strike: 9, Bid: 0.80, Ask: 1.00}
+
+	filled := security.Filled(contract, FillModel{Kind: FillMid})
+
+	if math.Abs(filled.Fill-0.90) > 0.000001 {
+		t.Errorf("Expected fill 0.90, got %f", filled.Fill)
+	}
+	if math.Abs(filled.BidStrikeRatio-10) > 0.000001 {
+		t.Errorf("Expected BidStrikeRatio 10, got %f", filled.BidStrikeRatio)
+	}
+	if math.Abs(filled.SafetySpread-19) > 0.000001 {
+		t.Errorf("Expected SafetySpread 19, got %f", filled.SafetySpread)
+	}
+}
+
+func TestNet(t *testing.T) {
+	security := Security{Price: 4}
+	fees := FeeSchedule{PerContract: 0.65, Regulatory: 0.05, PerOrder: 0.30, Assignment: 5}
+
+	put := Contract{Strike: 3.5, Fill: 0.10, DaysToExpiration: 7}
+	put = security.Net(put, fees, false)
+
+	// 0.10 * 100 - 0.65 - 0.05 - 0.30
+	if math.Abs(put.NetCredit-9) > 0.000001 {
+		t.Errorf("Expected put NetCredit 9, got %f", put.NetCredit)
+	}
+	if math.Abs(put.NetYield-100*9.0/350) > 0.000001 {
+		t.Errorf("Expected put NetYield %f, got %f", 100*9.0/350, put.NetYield)
+	}
+	if math.Abs(put.NetAnnualized-100*9.0/350*365/7) > 0.000001 {
+		t.Errorf("Expected put NetAnnualized %f, got %f", 100*9.0/350*365/7, put.NetAnnualized)
+	}
+	if math.Abs(put.Annualized-100*10.0/350*365/7) > 0.000001 {
+		t.Errorf("Expected put Annualized %f, got %f", 100*10.0/350*365/7, put.Annualized)
+	}
+
+	call := Contract{Strike: 4.5, Fill: 0.10, DaysToExpiration: 7}
+	call = security.Net(call, fees, true)
+
+	if math.Abs(call.NetYield-100*9.0/400) > 0.000001 {
+		t.Errorf("Expected call NetYield %f, got %f", 100*9.0/400, call.NetYield)
+	}
+	// (9 + 50 - 5) / 400
+	if math.Abs(call.NetIfCalled-100*54.0/400) > 0.000001 {
+		t.Errorf("Expected call NetIfCalled %f, got %f", 100*54.0/400, call.NetIfCalled)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	p := Params{
+		Initials: "eb",
+		PutCols:  []string{"ticker", "strike", "bid"},
+		CallCols: []string{"ticker", "strike", "bid"},
+	}
+	security := &Security{Ticker: "KO", Price: 60, Puts: []Contract{{Strike: 58, Bid: 0.25, Fill: 0.25}}}
+	puts := []Selection{{Security: security, Contract: security.Puts[0]}}
+
+	putsSheet, callsSheet := Print(puts, nil, "2024-06-07", p)
+
+	if putsSheet.Name != "eb_2024-06-07_puts.csv" || putsSheet.Rows != 1 {
+		t.Errorf("Unexpected puts sheet %+v", putsSheet)
+	}
+	if callsSheet.Name != "eb_2024-06-07_calls.csv" || callsSheet.Rows != 0 {
+		t.Errorf("Unexpected calls sheet %+v", callsSheet)
+	}
+
+	contents, err := os.ReadFile(putsSheet.Name)
+	if err != nil || strings.Count(string(contents), "\n") != HeaderRows+1 {
+		t.Errorf("Unexpected puts file %q %v", contents, err)
+	}
+	_, err = os.Stat(callsSheet.Name)
+	if !os.IsNotExist(err) {
+		t.Errorf("Expected no calls file, got %v", err)
+	}
+
+	err = Placeholder(callsSheet, "2024-06-07", p)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	contents, err = os.ReadFile(callsSheet.Name)
+	if err != nil || !strings.HasPrefix(string(contents), "No contracts matched profile eb") {
+		t.Errorf("Unexpected placeholder %q %v", contents, err)
+	}
+}
+
+func TestAlertRuleMatches(t *testing.T) {
+	ko := &Security{Ticker: "KO", EarningsDate: "2024-06-05"}
+	t1 := &Security{Ticker: "T"}
+	standout := AlertRule{Name: "standout", Type: "put", MinAnnualized: 50, MaxDelta: 0.2, NoEarnings: true, Webhook: "x"}
+
+	testCases := []struct {
+		selection Selection
+		expected  bool
+	}{
+		{Selection{Security: t1, Contract: Contract{Expiration: "2024-06-07", Annualized: 60, Delta: -0.15}}, true},
+		{Selection{Security: t1, Contract: Contract{Expiration: "2024-06-07", Annualized: 40, Delta: -0.15}}, false},
+		{Selection{Security: t1, Contract: Contract{Expiration: "2024-06-07", Annualized: 60, Delta: -0.25}}, false},
+		{Selection{Security: t1, Contract: Contract{Expiration: "2024-06-07", Annualized: 60, Delta: 0.15}, Call: true}, false},
+		{Selection{Security: ko, Contract: Contract{Expiration: "2024-06-07", Annualized: 60, Delta: -0.15}}, false},
+		{Selection{Security: ko, Contract: Contract{Expiration: "2024-05-31", Annualized: 60, Delta: -0.15}}, true},
+	}
+
+	for _, testCase := range testCases {
+		answer := standout.Matches(testCase.selection)
+		if answer != testCase.expected {
+			t.Errorf("ERROR: For %s %+v expected %v, got %v", testCase.selection.Security.Ticker, testCase.selection.Contract, testCase.expected, answer)
+		}
+	}
+
+	for _, rule := range []AlertRule{{Name: "none", MinAnnualized: 50}, {Name: "empty", Webhook: "x"}, {Name: "type", Type: "straddle", MaxDelta: 0.2, Webhook: "x"}} {
+		if rule.Validate() == nil {
+			t.Errorf("ERROR: For %s expected an error", rule.Name)
+		}
+	}
+	if err := standout.Validate(); err != nil {
+		t.Errorf("ERROR: For %s expected no error, got %s", standout.Name, err)
+	}
+}
@@ -0,0 +1,171 @@
+package sizing
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/erikbryant/options/security"
+)
+
+// Summary is the portfolio a sizing proposes
+type Summary struct {
+	Trades     int
+	Lots       int
+	Collateral float64 // cash (puts) or shares (calls) tied up
+	Premium    float64 // net premium collected
+}
+
+// Yield returns the collateral-weighted net yield of the portfolio, in percent
+func (s Summary) Yield() float64 {
+	if s.Collateral == 0 {
+		return 0
+	}
+	return 100 * s.Premium / s.Collateral
+}
+
+// String formats the summary for the run report
+func (s Summary) String() string {
+	return fmt.Sprintf("%d trades, %d lots, collateral $%.2f, premium $%.2f, yield %.2f%%", s.Trades, s.Lots, s.Collateral, s.Premium, s.Yield())
+}
+
+// Kelly returns the fraction of the portfolio to risk given the probability
+// of winning. Like the KellyCriterion sheet column, the odds are derived from
+// the probability itself: f = p - (1-p)/(p/(1-p)).
+func Kelly(p float64) float64 {
+	if p <= 0 || p >= 1 {
+		return 0
+	}
+	return p - (1-p)/(p/(1-p))
+}
+
+// winProbability returns the win factor the KellyCriterion sheet column uses
+// for the selection. Puts have negative deltas and we do not want to be
+// assigned, so we use the inverse.
+func winProbability(sel security.Selection) float64 {
+	if sel.Call {
+		return math.Abs(sel.Contract.Delta)
+	}
+	return 1 + sel.Contract.Delta
+}
+
+// collateral returns what one lot of the selection ties up
+func collateral(sel security.Selection) float64 {
+	lotSize := float64(sel.Contract.Shares())
+	if sel.Call {
+		return sel.Security.Price * lotSize
+	}
+	return sel.Contract.Strike * lotSize
+}
+
+// Concentration returns a warning for each sector that more of the suggested
+// trades share than the profile allows. Suggested trades are those given lots
+// or, if the profile does not size trades, every selection. Tickers with more
+// than one suggested trade count once.
+func Concentration(p security.Params, sheets ...[]security.Selection) []string {
+	if p.MaxSectorTrades <= 0 {
+		return nil
+	}
+
+	bySector := map[string][]string{}
+	for _, sheet := range sheets {
+		for _, sel := range sheet {
+			if p.Cash > 0 && sel.Contract.Lots == 0 {
+				continue
+			}
+			sector := sel.Security.Sector
+			if sector == "" || slices.Contains(bySector[sector], sel.Security.Ticker) {
+				continue
+			}
+			bySector[sector] = append(bySector[sector], sel.Security.Ticker)
+		}
+	}
+
+	sectors := []string{}
+	for sector := range bySector {
+		sectors = append(sectors, sector)
+	}
+	sort.Strings(sectors)
+
+	warnings := []string{}
+	for _, sector := range sectors {
+		tickers := bySector[sector]
+		if len(tickers) > p.MaxSectorTrades {
+			warnings = append(warnings, fmt.Sprintf("%d suggested trades in %s (max %d): %v", len(tickers), sector, p.MaxSectorTrades, tickers))
+		}
+	}
+
+	return warnings
+}
+
+// Size proposes a number of lots for each selection, best net annualized
+// return first, within the profile's cash, Kelly and exposure limits, and
+// returns the resulting portfolio. All of the selections draw on the same cash.
+func Size(p security.Params, sheets ...[]security.Selection) Summary {
+	summary := Summary{}
+
+	var all []*security.Selection
+	for _, sheet := range sheets {
+		for i := range sheet {
+			sheet[i].Contract.Lots = 0
+			all = append(all, &sheet[i])
+		}
+	}
+
+	if p.Cash <= 0 {
+		return summary
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Contract.NetAnnualized > all[j].Contract.NetAnnualized
+	})
+
+	cash := p.Cash
+	byTicker := map[string]float64{}
+	bySector := map[string]float64{}
+
+	for _, sel := range all {
+		perLot := collateral(*sel)
+		if perLot <= 0 {
+			continue
+		}
+
+		kelly := Kelly(winProbability(*sel))
+		if p.MaxKelly > 0 {
+			kelly = math.Min(kelly, p.MaxKelly)
+		}
+		if kelly <= 0 {
+			continue
+		}
+
+		budget := math.Min(kelly*p.Cash, cash)
+		if p.MaxTickerPct > 0 {
+			budget = math.Min(budget, p.MaxTickerPct/100*p.Cash-byTicker[sel.Security.Ticker])
+		}
+		sector := sel.Security.Sector
+		if p.MaxSectorPct > 0 && sector != "" {
+			budget = math.Min(budget, p.MaxSectorPct/100*p.Cash-bySector[sector])
+		}
+
+		lots := int(budget / perLot)
+		if lots <= 0 {
+			continue
+		}
+
+		sel.Contract.Lots = lots
+		exposure := float64(lots) * perLot
+		cash -= exposure
+		byTicker[sel.Security.Ticker] += exposure
+		if sector != "" {
+			bySector[sector] += exposure
+		}
+
+		summary.Trades++
+		summary.Lots += lots
+		summary.Collateral += exposure
+		summary.Premium += float64(lots) * sel.Contract.NetCredit
+	}
+
+	return summary
+}
@@ -0,0 +1,122 @@
+package sizing
+
+import (
+	"math"
+	"testing"
+
+	"github.com/erikbryant/options/security"
+)
+
+func TestKelly(t *testing.T) {
+	testCases := []struct {
+		p        float64
+		expected float64
+	}{
+		{0, 0},
+		{1, 0},
+		{0.5, 0},
+		{0.8, 0.8 - 0.2/4},
+		{0.3, 0.3 - 0.7/(0.3/0.7)},
+	}
+
+	for _, testCase := range testCases {
+		answer := Kelly(testCase.p)
+		if math.Abs(answer-testCase.expected) > 0.000001 {
+			t.Errorf("For %f expected %f, got %f", testCase.p, testCase.expected, answer)
+		}
+	}
+}
+
+func TestSize(t *testing.T) {
+	a := security.Security{Ticker: "A", Price: 10, Sector: "Tech"}
+	b := security.Security{Ticker: "B", Price: 20, Sector: "Tech"}
+	c := security.Security{Ticker: "C", Price: 50, Sector: "Energy"}
+
+	puts := []security.Selection{
+		// Kelly(0.8) = 0.75
+		{Security: &a, Contract: security.Contract{Strike: 10, LotSize: 100, Delta: -0.2, NetCredit: 20, NetAnnualized: 30}},
+		{Security: &b, Contract: security.Contract{Strike: 20, LotSize: 100, Delta: -0.2, NetCredit: 30, NetAnnualized: 20}},
+		// Kelly(0.4) < 0
+		{Security: &c, Contract: security.Contract{Strike: 50, LotSize: 100, Delta: -0.6, NetCredit: 90, NetAnnualized: 50}},
+	}
+	calls := []security.Selection{
+		{Security: &c, Call: true, Contract: security.Contract{Strike: 55, LotSize: 100, Delta: 0.9, NetCredit: 40, NetAnnualized: 10}},
+	}
+
+	p := security.Params{
+		Cash:         20000,
+		MaxTickerPct: 20,
+		MaxSectorPct: 30,
+		MaxKelly:     0.5,
+	}
+
+	summary := Size(p, puts, calls)
+
+	// A: ticker limit $4000 -> 4 lots of $1000
+	if puts[0].Contract.Lots != 4 {
+		t.Errorf("Expected 4 lots of A, got %d", puts[0].Contract.Lots)
+	}
+	// B: sector limit $6000 - $4000 -> 1 lot of $2000
+	if puts[1].Contract.Lots != 1 {
+		t.Errorf("Expected 1 lot of B, got %d", puts[1].Contract.Lots)
+	}
+	// C put: negative Kelly
+	if puts[2].Contract.Lots != 0 {
+		t.Errorf("Expected 0 lots of C put, got %d", puts[2].Contract.Lots)
+	}
+	// C call: ticker limit $4000 -> 0 lots of $5000
+	if calls[0].Contract.Lots != 0 {
+		t.Errorf("Expected 0 lots of C call, got %d", calls[0].Contract.Lots)
+	}
+
+	expected := Summary{Trades: 2, Lots: 5, Collateral: 6000, Premium: 110}
+	if summary != expected {
+		t.Errorf("Expected %v, got %v", expected, summary)
+	}
+}
+
+func TestSizeNoCash(t *testing.T) {
+	a := security.Security{Ticker: "A", Price: 10}
+	puts := []security.Selection{
+		{Security: &a, Contract: security.Contract{Strike: 10, Delta: -0.2, Lots: 3}},
+	}
+
+	summary := Size(security.Params{}, puts)
+
+	if puts[0].Contract.Lots != 0 || summary.Trades != 0 {
+		t.Errorf("Expected no sizing without cash, got %d lots %v", puts[0].Contract.Lots, summary)
+	}
+}
+
+func TestConcentration(t *testing.T) {
+	a := security.Security{Ticker: "A", Sector: "Tech"}
+	b := security.Security{Ticker: "B", Sector: "Tech"}
+	c := security.Security{Ticker: "C", Sector: "Energy"}
+	d := security.Security{Ticker: "D"}
+
+	puts := []security.Selection{
+		{Security: &a, Contract: security.Contract{Lots: 1}},
+		{Security: &a, Contract: security.Contract{Lots: 1}},
+		{Security: &b, Contract: security.Contract{Lots: 0}},
+		{Security: &c, Contract: security.Contract{Lots: 1}},
+		{Security: &d, Contract: security.Contract{Lots: 1}},
+	}
+	calls := []security.Selection{
+		{Security: &b, Call: true, Contract: security.Contract{Lots: 2}},
+	}
+
+	warnings := Concentration(security.Params{Cash: 1000, MaxSectorTrades: 1}, puts, calls)
+	if len(warnings) != 1 {
+		t.Fatalf("Expected 1 warning, got %v", warnings)
+	}
+
+	// Without sizing, every selection counts
+	warnings = Concentration(security.Params{MaxSectorTrades: 2}, puts)
+	if len(warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", warnings)
+	}
+	warnings = Concentration(security.Params{MaxSectorTrades: 1}, puts)
+	if len(warnings) != 1 {
+		t.Errorf("Expected 1 warning, got %v", warnings)
+	}
+}
@@ -1,48 +1,241 @@
 package skiplist
 
-// Skip is a list of symbols that we do not want to trade in
-var Skip = []string{
-	// Cannabis
-	"ACB",
-	"CGC",
-	"MSOS",
-	"SNDL",
-	"TLRY",
-
-	// Leveraged ETFs
-	"ERX",
-	"FAS",
-	"JNUG",
-	"LABD",
-	"LABU",
-	"NUGT",
-	"SDS",
-	"SLV",
-	"SPXU",
-	"SQQQ",
-	"TNA",
-	"TQQQ",
-	"UCO",
-	"UPRO",
-	"UVXY",
-	"VIXY",
-	"VXX",
-	"YINN",
-
-	// Indexes
-	"DJX",
-	"MRUT",
-	"MXACW",
-	"MXEA",
-	"MXEF",
-	"MXUSA",
-	"MXWLD",
-	"NANOS",
-	"OEX",
-	"RUT",
-	"SPX",
-	"VIX",
-	"XEO",
-	"XSP",
-	"ZS",
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/erikbryant/options/report"
+	"github.com/erikbryant/options/security"
+)
+
+// Rule describes securities we do not want to trade in. A rule matches when
+// every matcher it sets matches.
+type Rule struct {
+	// Matchers
+	Ticker      string  `json:"ticker,omitempty"`
+	NamePattern string  `json:"namePattern,omitempty"` // regular expression on the company/fund name
+	PriceBelow  float64 `json:"priceBelow,omitempty"`
+	Sector      string  `json:"sector,omitempty"` // sector or industry
+
+	// Annotations
+	Reason   string   `json:"reason"`
+	Owner    string   `json:"owner,omitempty"`
+	Expires  string   `json:"expires,omitempty"`  // YYYY-MM-DD; the rule no longer applies after this day
+	Profiles []string `json:"profiles,omitempty"` // only apply to these profiles (all if empty)
+
+	name *regexp.Regexp
+}
+
+// Rules is the contents of a skip rule file
+type Rules struct {
+	Version int    `json:"version"`
+	Deny    []Rule `json:"skip"`
+	Allow   []Rule `json:"allow"` // exceptions to the skip rules
+}
+
+// compile validates the rule and prepares it for matching
+func (rule *Rule) compile() error {
+	if rule.Reason == "" {
+		return fmt.Errorf("rule %+v has no reason", *rule)
+	}
+	if rule.Ticker == "" && rule.NamePattern == "" && rule.PriceBelow == 0 && rule.Sector == "" {
+		return fmt.Errorf("rule '%s' matches nothing", rule.Reason)
+	}
+	if rule.Expires != "" {
+		_, err := time.Parse("2006-01-02", rule.Expires)
+		if err != nil {
+			return fmt.Errorf("rule '%s' has a bad expiry %s", rule.Reason, err)
+		}
+	}
+	if rule.NamePattern != "" {
+		var err error
+		rule.name, err = regexp.Compile(rule.NamePattern)
+		if err != nil {
+			return fmt.Errorf("rule '%s' has a bad name pattern %s", rule.Reason, err)
+		}
+	}
+	return nil
+}
+
+// current returns whether the rule has not yet expired on the given day
+func (rule Rule) current(today string) bool {
+	return rule.Expires == "" || rule.Expires >= today
+}
+
+// active returns whether the rule applies to the profile ("" for the whole
+// run, which only global rules apply to) on the given day
+func (rule Rule) active(profile, today string) bool {
+	if !rule.current(today) {
+		return false
+	}
+	if len(rule.Profiles) == 0 {
+		return true
+	}
+	return profile != "" && slices.Contains(rule.Profiles, profile)
+}
+
+// tickerOnly returns whether the rule can be decided from the ticker alone
+func (rule Rule) tickerOnly() bool {
+	return rule.NamePattern == "" && rule.PriceBelow == 0 && rule.Sector == ""
+}
+
+// matches returns whether the rule matches the security
+func (rule Rule) matches(sec *security.Security) bool {
+	if rule.Ticker != "" && rule.Ticker != sec.Ticker {
+		return false
+	}
+	if rule.name != nil && !rule.name.MatchString(sec.Name) {
+		return false
+	}
+	if rule.PriceBelow != 0 && sec.Price >= rule.PriceBelow {
+		return false
+	}
+	if rule.Sector != "" && rule.Sector != sec.Sector && rule.Sector != sec.Industry {
+		return false
+	}
+	return true
+}
+
+// Parse parses the contents of a skip rule file
+func Parse(contents []byte) (*Rules, error) {
+	rules := &Rules{}
+
+	err := json.Unmarshal(contents, rules)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal skip rules %s", err)
+	}
+
+	if rules.Version != 1 {
+		return nil, fmt.Errorf("unsupported skip rule version %d", rules.Version)
+	}
+
+	for i := range rules.Deny {
+		err = rules.Deny[i].compile()
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i := range rules.Allow {
+		err = rules.Allow[i].compile()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rules, nil
+}
+
+// Load reads the skip rules from file
+func Load(file string) (*Rules, error) {
+	contents, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read skip rules %s %s", file, err)
+	}
+
+	rules, err := Parse(contents)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err)
+	}
+
+	return rules, nil
+}
+
+// allowed returns whether an allow rule for the profile matches the security.
+// For the whole run ("") an allow rule for any profile will do, so the
+// security reaches that profile.
+func (rules *Rules) allowed(sec *security.Security, profile, today string) bool {
+	for _, rule := range rules.Allow {
+		if !rule.matches(sec) {
+			continue
+		}
+		if profile == "" && rule.current(today) || rule.active(profile, today) {
+			return true
+		}
+	}
+	return false
+}
+
+// tickerAllowed returns whether an allow rule, for any profile, names the ticker
+func (rules *Rules) tickerAllowed(ticker, today string) bool {
+	for _, rule := range rules.Allow {
+		if rule.Ticker == ticker && rule.current(today) {
+			return true
+		}
+	}
+	return false
+}
+
+// SkipTicker returns whether (and why) the whole run should skip the ticker
+// before fetching any data for it. Only global rules that need nothing but
+// the ticker are considered, and only if no allow rule names the ticker.
+// Allow rules that need the security's data (name, price or sector) are
+// only considered once it is fetched, so they cannot override these.
+func (rules *Rules) SkipTicker(ticker, today string) (string, bool) {
+	if rules.tickerAllowed(ticker, today) {
+		return "", false
+	}
+
+	sec := &security.Security{Ticker: ticker}
+	for _, rule := range rules.Deny {
+		if rule.tickerOnly() && rule.active("", today) && rule.matches(sec) {
+			return rule.Reason, true
+		}
+	}
+
+	return "", false
+}
+
+// Skip returns whether (and why) the profile ("" for the whole run) should
+// skip the security. The whole run keeps a security any profile allows;
+// Selections then skips it for the profiles that do not.
+func (rules *Rules) Skip(sec *security.Security, profile, today string) (string, bool) {
+	if rules.allowed(sec, profile, today) {
+		return "", false
+	}
+
+	for _, rule := range rules.Deny {
+		if rule.active(profile, today) && rule.matches(sec) {
+			return rule.Reason, true
+		}
+	}
+
+	return "", false
+}
+
+// Tickers returns the tickers the whole run should not skip, recording the
+// reason for each one it does in the run report
+func (rules *Rules) Tickers(tickers []string, today string) []string {
+	result := []string{}
+	for _, ticker := range tickers {
+		reason, skip := rules.SkipTicker(ticker, today)
+		if skip {
+			report.Exclude(ticker, "", reason)
+			continue
+		}
+		result = append(result, ticker)
+	}
+	return result
+}
+
+// Selections returns the selections the profile should not skip, recording
+// the reason for each ticker it does in the run report
+func (rules *Rules) Selections(selections []security.Selection, profile, today string) []security.Selection {
+	result := []security.Selection{}
+	excluded := map[string]bool{}
+	for _, sel := range selections {
+		reason, skip := rules.Skip(sel.Security, profile, today)
+		if skip {
+			if !excluded[sel.Security.Ticker] {
+				report.Exclude(sel.Security.Ticker, profile, reason)
+				excluded[sel.Security.Ticker] = true
+			}
+			continue
+		}
+		result = append(result, sel)
+	}
+	return result
 }
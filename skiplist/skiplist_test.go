@@ -0,0 +1,165 @@
+package skiplist
+
+import (
+	"testing"
+
+	"github.com/erikbryant/options/security"
+)
+
+const testRules = `{
+ "version": 1,
+ "skip": [
+  {"ticker": "ACB", "reason": "cannabis", "owner": "eb"},
+  {"ticker": "OLD", "reason": "halted", "expires": "2024-01-31"},
+  {"namePattern": "(?i)\\b(ultra|ultrapro|2x|3x|bear)\\b", "reason": "leveraged ETF"},
+  {"priceBelow": 1.0, "reason": "penny stock"},
+  {"sector": "Tobacco", "reason": "tobacco", "profiles": ["eb"]}
+ ],
+ "allow": [
+  {"ticker": "TQQQ", "reason": "hedging", "profiles": ["cc"]},
+  {"sector": "Utilities", "reason": "income", "profiles": ["eb"]}
+ ]
+}`
+
+func TestParseError(t *testing.T) {
+	testCases := []string{
+		`not json`,
+		`{"version": 2, "skip": []}`,
+		`{"version": 1, "skip": [{"ticker": "ACB"}]}`,
+		`{"version": 1, "skip": [{"reason": "nothing"}]}`,
+		`{"version": 1, "skip": [{"ticker": "ACB", "reason": "r", "expires": "soon"}]}`,
+		`{"version": 1, "skip": [{"namePattern": "(", "reason": "r"}]}`,
+		`{"version": 1, "allow": [{"reason": "r"}]}`,
+	}
+
+	for _, testCase := range testCases {
+		_, err := Parse([]byte(testCase))
+		if err == nil {
+			t.Errorf("ERROR: For %s expected an error", testCase)
+		}
+	}
+}
+
+func TestSkip(t *testing.T) {
+	rules, err := Parse([]byte(testRules))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	testCases := []struct {
+		sec     security.Security
+		profile string
+		today   string
+		reason  string
+		skip    bool
+	}{
+		{security.Security{Ticker: "ACB", Price: 5}, "", "2024-01-01", "cannabis", true},
+		{security.Security{Ticker: "KO", Price: 60}, "", "2024-01-01", "", false},
+		{security.Security{Ticker: "OLD", Price: 5}, "", "2024-01-31", "halted", true},
+		{security.Security{Ticker: "OLD", Price: 5}, "", "2024-02-01", "", false},
+		// Kept for the run, since cc allows it
+		{security.Security{Ticker: "TQQQ", Name: "ProShares UltraPro QQQ", Price: 50}, "", "2024-01-01", "", false},
+		{security.Security{Ticker: "TQQQ", Name: "ProShares UltraPro QQQ", Price: 50}, "eb", "2024-01-01", "leveraged ETF", true},
+		{security.Security{Ticker: "TQQQ", Name: "ProShares UltraPro QQQ", Price: 50}, "cc", "2024-01-01", "", false},
+		{security.Security{Ticker: "ULTA", Name: "Ulta Beauty Inc", Price: 50}, "", "2024-01-01", "", false},
+		{security.Security{Ticker: "CENT", Price: 0.5}, "", "2024-01-01", "penny stock", true},
+		{security.Security{Ticker: "MO", Price: 50, Sector: "Tobacco"}, "", "2024-01-01", "", false},
+		{security.Security{Ticker: "MO", Price: 50, Sector: "Tobacco"}, "cc", "2024-01-01", "", false},
+		{security.Security{Ticker: "MO", Price: 50, Sector: "Tobacco"}, "eb", "2024-01-01", "tobacco", true},
+	}
+
+	for _, testCase := range testCases {
+		reason, skip := rules.Skip(&testCase.sec, testCase.profile, testCase.today)
+		if reason != testCase.reason || skip != testCase.skip {
+			t.Errorf("ERROR: For %s/%s on %s expected %s %t, got %s %t", testCase.sec.Ticker, testCase.profile, testCase.today, testCase.reason, testCase.skip, reason, skip)
+		}
+	}
+}
+
+func TestSkipTicker(t *testing.T) {
+	rules, err := Parse([]byte(testRules))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	testCases := []struct {
+		ticker string
+		skip   bool
+	}{
+		{"ACB", true},
+		{"KO", false},
+		{"OLD", false},  // expired
+		{"CENT", false}, // needs the price to decide
+		{"MO", false},   // profile rule
+		{"TQQQ", false}, // allowed by name
+	}
+
+	for _, testCase := range testCases {
+		_, skip := rules.SkipTicker(testCase.ticker, "2024-06-01")
+		if skip != testCase.skip {
+			t.Errorf("ERROR: For %s expected %t, got %t", testCase.ticker, testCase.skip, skip)
+		}
+	}
+}
+
+func TestProfileAllow(t *testing.T) {
+	rules, err := Parse([]byte(testRules))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	today := "2024-06-01"
+
+	// As a scan does: skip tickers, then securities for the whole run, then
+	// each profile's selections
+	tickers := rules.Tickers([]string{"ACB", "TQQQ", "SQQQ"}, today)
+	if len(tickers) != 2 {
+		t.Fatalf("Unexpected tickers %v", tickers)
+	}
+
+	securities := []security.Security{}
+	for _, sec := range []security.Security{
+		{Ticker: "TQQQ", Name: "ProShares UltraPro QQQ", Price: 50},
+		{Ticker: "SQQQ", Name: "ProShares UltraPro Short QQQ", Price: 10},
+	} {
+		if _, skip := rules.Skip(&sec, "", today); !skip {
+			securities = append(securities, sec)
+		}
+	}
+	if len(securities) != 1 || securities[0].Ticker != "TQQQ" {
+		t.Fatalf("Unexpected securities %v", securities)
+	}
+
+	selections := []security.Selection{{Security: &securities[0]}}
+	testCases := []struct {
+		profile  string
+		expected int
+	}{
+		{"cc", 1},
+		{"eb", 0},
+	}
+	for _, testCase := range testCases {
+		answer := rules.Selections(selections, testCase.profile, today)
+		if len(answer) != testCase.expected {
+			t.Errorf("ERROR: For %s expected %d selections, got %d", testCase.profile, testCase.expected, len(answer))
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	rules, err := Load("../skiplist.json")
+	if err != nil {
+		t.Fatalf("Unable to load the skip rules %s", err)
+	}
+
+	_, skip := rules.SkipTicker("SQQQ", "2024-06-01")
+	if !skip {
+		t.Errorf("ERROR: Expected SQQQ to be skipped")
+	}
+
+	// Only the tickers that were always skipped; anything broader changes the universe
+	for _, rule := range rules.Deny {
+		if rule.Ticker == "" || rule.NamePattern != "" || rule.PriceBelow != 0 || rule.Sector != "" {
+			t.Errorf("ERROR: Expected only ticker rules, got %+v", rule)
+		}
+	}
+}
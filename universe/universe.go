@@ -0,0 +1,243 @@
+package universe
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/erikbryant/options/cboe"
+	"github.com/erikbryant/options/positions"
+	"github.com/erikbryant/options/utils"
+)
+
+var (
+	// WatchlistDir holds the watchlist:NAME files
+	WatchlistDir = "watchlists"
+	// IndexDir holds the index:NAME constituent files
+	IndexDir = "indexes"
+	// PositionsFile is where the holdings source finds what we hold
+	PositionsFile = "positions.csv"
+	// SnapshotDir is where resolved universes are saved, by date
+	SnapshotDir = "universe-cache"
+)
+
+// sources returns the tickers for each kind of source, given its argument
+var sources = map[string]func(arg string) ([]string, error){
+	"cboe": func(arg string) ([]string, error) {
+		records, err := cboe.WeeklySymbols()
+		if err != nil {
+			return nil, err
+		}
+		switch arg {
+		case "":
+			return cboe.Tickers(records, nil), nil
+		case "etf":
+			return cboe.Tickers(records, cboe.Record.IsETF), nil
+		case "equity":
+			return cboe.Tickers(records, cboe.Record.IsEquity), nil
+		}
+		return nil, fmt.Errorf("unknown CBOE product type '%s'", arg)
+	},
+	"watchlist": func(arg string) ([]string, error) {
+		return readList(filepath.Join(WatchlistDir, arg+".txt"))
+	},
+	"index": func(arg string) ([]string, error) {
+		return readList(filepath.Join(IndexDir, arg+".txt"))
+	},
+	"file": readList,
+	"holdings": func(arg string) ([]string, error) {
+		held, err := positions.Load(PositionsFile)
+		if err != nil {
+			return nil, err
+		}
+		return positions.Tickers(held), nil
+	},
+}
+
+// Snapshot is a resolved universe, saved so a run can be reproduced
+type Snapshot struct {
+	Date       string   `json:"date"`
+	Expression string   `json:"expression"`
+	Tickers    []string `json:"tickers"`
+	// Names are the CBOE directory's names for the tickers, so name-based
+	// skip rules still work when the day is replayed
+	Names map[string]string `json:"names,omitempty"`
+}
+
+// readList returns the tickers in a list file: one per line (or separated by
+// commas), with blank lines and # comments ignored
+func readList(file string) ([]string, error) {
+	contents, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read ticker list %s", err)
+	}
+
+	tickers := []string{}
+	for _, line := range strings.Split(string(contents), "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		for _, ticker := range strings.Split(line, ",") {
+			ticker = strings.TrimSpace(ticker)
+			if ticker != "" {
+				tickers = append(tickers, ticker)
+			}
+		}
+	}
+
+	return tickers, nil
+}
+
+// normalize returns the tickers upper-cased, sorted and without duplicates
+func normalize(tickers []string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, ticker := range tickers {
+		ticker = strings.ToUpper(strings.TrimSpace(ticker))
+		if ticker == "" || seen[ticker] {
+			continue
+		}
+		seen[ticker] = true
+		result = append(result, ticker)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// union returns the tickers in either list
+func union(a, b []string) []string {
+	return normalize(append(append([]string{}, a...), b...))
+}
+
+// intersect returns the tickers in both lists
+func intersect(a, b []string) []string {
+	in := map[string]bool{}
+	for _, ticker := range b {
+		in[ticker] = true
+	}
+
+	result := []string{}
+	for _, ticker := range a {
+		if in[ticker] {
+			result = append(result, ticker)
+		}
+	}
+	return normalize(result)
+}
+
+// source returns the tickers for a source such as cboe, cboe:etf, watchlist:eb,
+// index:sp500, file:path/to/list.txt or holdings
+func source(term string) ([]string, error) {
+	kind, arg, _ := strings.Cut(term, ":")
+
+	load, ok := sources[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown universe source '%s'", term)
+	}
+
+	tickers, err := load(arg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load universe source '%s' %s", term, err)
+	}
+
+	return normalize(tickers), nil
+}
+
+// Resolve evaluates a universe expression: sources separated by the set
+// operators + (union), & (intersection) and - (minus), applied left to right.
+// For example, "cboe + watchlist:eb - index:excluded & holdings".
+func Resolve(expression string) ([]string, error) {
+	terms := strings.Fields(expression)
+	if len(terms) == 0 || len(terms)%2 == 0 {
+		return nil, fmt.Errorf("malformed universe expression '%s'", expression)
+	}
+
+	result, err := source(terms[0])
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 1; i < len(terms); i += 2 {
+		tickers, err := source(terms[i+1])
+		if err != nil {
+			return nil, err
+		}
+
+		switch terms[i] {
+		case "+":
+			result = union(result, tickers)
+		case "&":
+			result = intersect(result, tickers)
+		case "-":
+			result = utils.Remove(result, tickers)
+		default:
+			return nil, fmt.Errorf("unknown universe operator '%s' in '%s'", terms[i], expression)
+		}
+	}
+
+	return result, nil
+}
+
+// snapshotFile returns where the universe for the given day is saved
+func snapshotFile(day string) string {
+	return filepath.Join(SnapshotDir, day+".json")
+}
+
+// Save records the universe resolved on the given day
+func Save(snapshot Snapshot) error {
+	err := os.MkdirAll(SnapshotDir, 0755)
+	if err != nil {
+		return fmt.Errorf("unable to create %s %s", SnapshotDir, err)
+	}
+
+	s, err := json.MarshalIndent(snapshot, "", " ")
+	if err != nil {
+		return fmt.Errorf("could not marshal universe %s", err)
+	}
+
+	return os.WriteFile(snapshotFile(snapshot.Date), s, 0644)
+}
+
+// Load returns the universe saved on the given day
+func Load(day string) (Snapshot, error) {
+	var snapshot Snapshot
+
+	contents, err := os.ReadFile(snapshotFile(day))
+	if err != nil {
+		return snapshot, fmt.Errorf("no universe saved for %s %s", day, err)
+	}
+
+	err = json.Unmarshal(contents, &snapshot)
+	if err != nil {
+		return snapshot, fmt.Errorf("unable to unmarshal universe for %s %s", day, err)
+	}
+
+	return snapshot, nil
+}
+
+// Tickers returns the universe to scan. If replay is set, it is the universe
+// saved on that day. Otherwise the expression is resolved and saved as
+// today's universe.
+func Tickers(expression, today, replay string) ([]string, error) {
+	if replay != "" {
+		snapshot, err := Load(replay)
+		if err != nil {
+			return nil, err
+		}
+		cboe.Remember(snapshot.Names)
+		return snapshot.Tickers, nil
+	}
+
+	tickers, err := Resolve(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	err = Save(Snapshot{Date: today, Expression: expression, Tickers: tickers, Names: cboe.Names(tickers)})
+	if err != nil {
+		return nil, err
+	}
+
+	return tickers, nil
+}
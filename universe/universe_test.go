@@ -0,0 +1,125 @@
+package universe
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/erikbryant/options/cboe"
+)
+
+// restore puts the package's settings back once the test is done
+func restore(t *testing.T) {
+	watchlistDir, indexDir, positionsFile, snapshotDir, cboe := WatchlistDir, IndexDir, PositionsFile, SnapshotDir, sources["cboe"]
+	t.Cleanup(func() {
+		WatchlistDir, IndexDir, PositionsFile, SnapshotDir, sources["cboe"] = watchlistDir, indexDir, positionsFile, snapshotDir, cboe
+	})
+}
+
+func TestResolve(t *testing.T) {
+	restore(t)
+	dir := t.TempDir()
+	WatchlistDir = dir
+	IndexDir = dir
+	PositionsFile = filepath.Join(dir, "positions.csv")
+
+	files := map[string]string{
+		"eb.txt":        "# Erik's watchlist\nko, t\npfe\n\n",
+		"sp500.txt":     "AAPL\nKO\nMSFT\nPFE\n",
+		"positions.csv": "ticker,type,quantity,expiration,strike\nT,stock,100\nMSFT,put,-1,2024-09-20,400\n",
+	}
+	for name, contents := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sources["cboe"] = func(arg string) ([]string, error) {
+		return []string{"AAPL", "KO", "SQQQ", "T"}, nil
+	}
+
+	testCases := []struct {
+		expression string
+		expected   []string
+	}{
+		{"cboe", []string{"AAPL", "KO", "SQQQ", "T"}},
+		{"watchlist:eb", []string{"KO", "PFE", "T"}},
+		{"holdings", []string{"MSFT", "T"}},
+		{"cboe + watchlist:eb", []string{"AAPL", "KO", "PFE", "SQQQ", "T"}},
+		{"cboe & index:sp500", []string{"AAPL", "KO"}},
+		{"cboe - holdings", []string{"AAPL", "KO", "SQQQ"}},
+		{"cboe + holdings & index:sp500 - watchlist:eb", []string{"AAPL", "MSFT"}},
+		{"file:" + filepath.Join(dir, "eb.txt"), []string{"KO", "PFE", "T"}},
+	}
+
+	for _, testCase := range testCases {
+		answer, err := Resolve(testCase.expression)
+		if err != nil {
+			t.Errorf("ERROR: For %s unexpected error %s", testCase.expression, err)
+			continue
+		}
+		if !slices.Equal(answer, testCase.expected) {
+			t.Errorf("ERROR: For %s expected %v, got %v", testCase.expression, testCase.expected, answer)
+		}
+	}
+
+	errorCases := []string{
+		"",
+		"cboe +",
+		"cboe * holdings",
+		"nasdaq",
+		"watchlist:missing",
+	}
+
+	for _, expression := range errorCases {
+		_, err := Resolve(expression)
+		if err == nil {
+			t.Errorf("ERROR: For '%s' expected an error", expression)
+		}
+	}
+}
+
+func TestTickers(t *testing.T) {
+	restore(t)
+	SnapshotDir = t.TempDir()
+
+	sources["cboe"] = func(arg string) ([]string, error) {
+		return []string{"KO", "T"}, nil
+	}
+
+	cboe.Remember(map[string]string{"KO": "Coca-Cola Co, The"})
+
+	tickers, err := Tickers("cboe", "2024-06-07", "")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if !slices.Equal(tickers, []string{"KO", "T"}) {
+		t.Errorf("Expected [KO T], got %v", tickers)
+	}
+
+	snapshot, err := Load("2024-06-07")
+	// The names are kept for the skip rules when the day is replayed
+	if err != nil || snapshot.Expression != "cboe" || snapshot.Names["KO"] != "Coca-Cola Co, The" || len(snapshot.Names) != 1 {
+		t.Errorf("Unexpected snapshot %+v %v", snapshot, err)
+	}
+
+	// The CBOE list changes, but replaying the day gives the same universe
+	sources["cboe"] = func(arg string) ([]string, error) {
+		return []string{"AAPL"}, nil
+	}
+
+	tickers, err = Tickers("cboe", "2024-06-14", "2024-06-07")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if !slices.Equal(tickers, []string{"KO", "T"}) {
+		t.Errorf("Expected [KO T], got %v", tickers)
+	}
+
+	_, err = Tickers("cboe", "2024-06-14", "2024-01-01")
+	if err == nil {
+		t.Errorf("Expected an error replaying a day with no saved universe")
+	}
+}
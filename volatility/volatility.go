@@ -0,0 +1,118 @@
+package volatility
+
+import (
+	"math"
+
+	"github.com/erikbryant/options/security"
+)
+
+// tradingDaysPerYear is used to annualize daily volatility
+const tradingDaysPerYear = 252
+
+// Realized returns the annualized standard deviation of the daily log returns
+// over the last 'days' closes (as a fraction, e.g. 0.25 for 25%). It returns
+// zero if there is not enough history.
+func Realized(candles []security.DayRange, days int) float64 {
+	if days < 2 || len(candles) < days+1 {
+		return 0
+	}
+
+	returns := []float64{}
+	for i := len(candles) - days; i < len(candles); i++ {
+		if candles[i-1].Close <= 0 || candles[i].Close <= 0 {
+			return 0
+		}
+		returns = append(returns, math.Log(candles[i].Close/candles[i-1].Close))
+	}
+
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns) - 1)
+
+	return math.Sqrt(variance) * math.Sqrt(tradingDaysPerYear)
+}
+
+// Rank returns where current falls between the low and high of history, from 0 to 100
+func Rank(current float64, history []float64) float64 {
+	if len(history) == 0 {
+		return 0
+	}
+
+	low := history[0]
+	high := history[0]
+	for _, h := range history {
+		low = math.Min(low, h)
+		high = math.Max(high, h)
+	}
+
+	if high == low {
+		return 0
+	}
+
+	rank := 100.0 * (current - low) / (high - low)
+
+	return math.Max(0, math.Min(100, rank))
+}
+
+// Percentile returns the percent of history that is below current
+func Percentile(current float64, history []float64) float64 {
+	if len(history) == 0 {
+		return 0
+	}
+
+	below := 0
+	for _, h := range history {
+		if h < current {
+			below++
+		}
+	}
+
+	return 100.0 * float64(below) / float64(len(history))
+}
+
+// nearest returns the IV of the contract with the strike nearest price in the
+// earliest expiration
+func nearest(contracts []security.Contract, price float64) (float64, bool) {
+	found := false
+	var best security.Contract
+
+	for _, contract := range contracts {
+		if contract.IV <= 0 {
+			continue
+		}
+		if !found ||
+			contract.Expiration < best.Expiration ||
+			(contract.Expiration == best.Expiration && math.Abs(contract.Strike-price) < math.Abs(best.Strike-price)) {
+			best = contract
+			found = true
+		}
+	}
+
+	return best.IV, found
+}
+
+// AtTheMoney returns the average IV of the put and call nearest the money in
+// the earliest expiration, or zero if there are none
+func AtTheMoney(sec security.Security) float64 {
+	put, okPut := nearest(sec.Puts, sec.Price)
+	call, okCall := nearest(sec.Calls, sec.Price)
+
+	switch {
+	case okPut && okCall:
+		return (put + call) / 2
+	case okPut:
+		return put
+	case okCall:
+		return call
+	}
+
+	return 0
+}
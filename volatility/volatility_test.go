@@ -0,0 +1,83 @@
+package volatility
+
+import (
+	"math"
+	"testing"
+
+	"github.com/erikbryant/options/security"
+)
+
+func TestRealized(t *testing.T) {
+	// Alternating up and down by the same factor
+	candles := []security.DayRange{}
+	price := 100.0
+	for i := 0; i < 31; i++ {
+		candles = append(candles, security.DayRange{Close: price})
+		if i%2 == 0 {
+			price *= math.Exp(0.01)
+		} else {
+			price *= math.Exp(-0.01)
+		}
+	}
+
+	// 30 returns of +/-1%, mean 0
+	expected := math.Sqrt(30.0*0.0001/29.0) * math.Sqrt(252)
+
+	answer := Realized(candles, 30)
+	if math.Abs(answer-expected) > 0.000001 {
+		t.Errorf("Expected %f, got %f", expected, answer)
+	}
+
+	// Not enough history
+	answer = Realized(candles, 60)
+	if answer != 0 {
+		t.Errorf("Expected 0, got %f", answer)
+	}
+}
+
+func TestRankPercentile(t *testing.T) {
+	testCases := []struct {
+		current    float64
+		history    []float64
+		rank       float64
+		percentile float64
+	}{
+		{0.3, []float64{}, 0, 0},
+		{0.3, []float64{0.3, 0.3}, 0, 0},
+		{0.3, []float64{0.2, 0.4}, 50, 50},
+		{0.5, []float64{0.2, 0.4}, 100, 100},
+		{0.1, []float64{0.2, 0.4}, 0, 0},
+		{0.25, []float64{0.2, 0.3, 0.4, 0.6}, 12.5, 25},
+	}
+
+	for _, testCase := range testCases {
+		rank := Rank(testCase.current, testCase.history)
+		if math.Abs(rank-testCase.rank) > 0.000001 {
+			t.Errorf("For %f %v expected rank %f, got %f", testCase.current, testCase.history, testCase.rank, rank)
+		}
+		percentile := Percentile(testCase.current, testCase.history)
+		if math.Abs(percentile-testCase.percentile) > 0.000001 {
+			t.Errorf("For %f %v expected percentile %f, got %f", testCase.current, testCase.history, testCase.percentile, percentile)
+		}
+	}
+}
+
+func TestAtTheMoney(t *testing.T) {
+	sec := security.Security{
+		Price: 10.2,
+		Puts: []security.Contract{
+			{Expiration: "2024-09-20", Strike: 10, IV: 0.9},
+			{Expiration: "2024-09-13", Strike: 9, IV: 0.5},
+			{Expiration: "2024-09-13", Strike: 10, IV: 0.4},
+		},
+		Calls: []security.Contract{
+			{Expiration: "2024-09-13", Strike: 10, IV: 0.3},
+			{Expiration: "2024-09-13", Strike: 11, IV: 0.2},
+		},
+	}
+
+	answer := AtTheMoney(sec)
+	if math.Abs(answer-0.35) > 0.000001 {
+		t.Errorf("Expected 0.35, got %f", answer)
+	}
+}